@@ -2,68 +2,146 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
 
+	"attendance-app/internal/api"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
 	db "attendance-app/internal/database"
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/metrics"
+	"attendance-app/internal/migrations"
+	"attendance-app/internal/repository"
 	"attendance-app/routes" // Import the routes package
 
-	"github.com/joho/godotenv"
 	"golang.ngrok.com/ngrok"
-	"golang.ngrok.com/ngrok/config"
+	ngrokconfig "golang.ngrok.com/ngrok/config"
 )
 
 func main() {
-	// Initialize the router with routes
-	router := routes.SetupRoutes()
+	// Load and validate the configuration before anything else
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Stop on Ctrl+C or when the deployment sends SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Run ngrok and start the server
-	if err := run(context.Background(), router); err != nil {
+	if err := run(ctx, cfg); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(ctx context.Context, router http.Handler) error {
-	// Load .env file
-	err := godotenv.Load()
+func run(ctx context.Context, cfg *config.Config) error {
+	// Write request and response logs to daily files
+	accessLog, err := helpers.InitAccessLog(cfg.Log.Dir, cfg.Log.RetentionDays)
+	if err != nil {
+		return err
+	}
+	defer accessLog.Close()
+
+	helpers.TrustProxies(cfg.Server.TrustedProxies)
 
+	// Initialize the database
+	if err := db.InitializeDB(ctx, cfg.Database); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Refuse to serve against a schema the code does not match
+	if err := migrations.Check(ctx, db.Pool); err != nil {
+		return err
+	}
+
+	metrics.RegisterPoolStats(db.Pool)
+
+	store := repository.NewStore(db.Pool, cfg.Database)
+	tokens := auth.NewTokenIssuer(cfg.Auth)
+
+	handler, err := api.NewHandler(cfg, tokens, store, store)
 	if err != nil {
-		log.Fatal("Error loading .env file")
-	} else {
-		log.Println("Successfully loaded .env file")
+		return err
 	}
+	handler.AddDatabaseChecks(db.Pool)
 
-	isDeploy, err := strconv.ParseBool(os.Getenv("NGROK_DEPLOY"))
+	server := &http.Server{
+		// Initialize the router with routes
+		Handler:      routes.SetupRoutes(cfg, handler, tokens, store),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 
+	listener, err := listen(ctx, cfg.Server)
 	if err != nil {
-		log.Println("Error parsing NGROK_DEPLOY")
+		return err
 	}
 
-	// Initialize the database
-	db.InitializeDB()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
 
-	if isDeploy {
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Stop accepting requests, closing the listener and with it the ngrok
+	// tunnel, and let in-flight uploads finish before the database closes
+	log.Println("Shutting down, waiting for in-flight requests to finish...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown did not finish: %w", err)
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	log.Println("Server stopped")
+
+	return nil
+}
+
+// listen opens the ngrok tunnel or a local port depending on the configuration.
+func listen(ctx context.Context, cfg config.ServerConfig) (net.Listener, error) {
+	if cfg.NgrokDeploy {
 		// Start ngrok tunnel
 		listener, err := ngrok.Listen(ctx,
-			config.HTTPEndpoint(),
-			ngrok.WithAuthtokenFromEnv(), // Uses the authtoken from the environment variables
+			ngrokconfig.HTTPEndpoint(),
+			ngrok.WithAuthtoken(cfg.NgrokAuthToken),
 		)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		log.Println("App URL", listener.URL())
 
-		return http.Serve(listener, router)
-	} else {
-		// Start the server
-		log.Println("Server running on port 8080...")
+		return listener, nil
+	}
 
-		return http.ListenAndServe(":8080", router)
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Port))
+	if err != nil {
+		return nil, err
 	}
 
-	// Start the server and use the router
-	// return http.Serve(listener, router)
+	// Start the server
+	log.Printf("Server running on port %d...", cfg.Port)
+
+	return listener, nil
 }
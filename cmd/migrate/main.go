@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"attendance-app/internal/config"
+	db "attendance-app/internal/database"
+	"attendance-app/internal/migrations"
+)
+
+const usage = `Usage: migrate [-config file] [-dry-run] <command> [steps]
+
+Commands:
+  status      list the migrations and whether they are applied
+  up [n]      apply the next n pending migrations, all when n is omitted
+  down [n]    roll back the last n applied migrations, one when n is omitted,
+              rolling back 0001 and 0002 keeps their tables and data
+
+Flags:
+`
+
+func main() {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	configFile := flags.String("config", "", "path to a .env style configuration file (default .env when present)")
+	dryRun := flags.Bool("dry-run", false, "print the SQL that would run without changing the database")
+
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	command := flags.Arg(0)
+
+	steps := 0
+	if flags.NArg() == 2 {
+		n, err := strconv.Atoi(flags.Arg(1))
+		if err != nil || n < 1 {
+			log.Fatalf("steps must be a positive number, got %q", flags.Arg(1))
+		}
+		steps = n
+	}
+
+	cfg, err := config.LoadDatabase(*configFile)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := db.InitializeDB(ctx, cfg); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	runner := migrations.NewRunner(db.Pool, os.Stdout)
+
+	switch command {
+	case "status":
+		err = printStatus(ctx, runner)
+	case "up":
+		err = runner.Up(ctx, steps, *dryRun)
+	case "down":
+		err = runner.Down(ctx, steps, *dryRun)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printStatus(ctx context.Context, runner *migrations.Runner) error {
+	statuses, err := runner.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/models"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// LockoutsHandler lists the operator accounts, usernames and IP addresses that
+// are currently locked out after failed logins.
+func (h *Handler) LockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.operators.GetLockedOperators(r.Context())
+	if err != nil {
+		logger.Println(r.Context(), "Error fetching locked operators:", err)
+		helpers.SetResponse(w, r, "Failed to get data", nil, http.StatusInternalServerError)
+		return
+	}
+
+	// Locked operators are already listed, only add usernames that do not
+	// belong to an operator
+	lockedOperators := make(map[string]bool)
+	for _, lockout := range lockouts {
+		lockedOperators[strings.ToLower(lockout.Key)] = true
+	}
+
+	for _, state := range h.usernameThrottle.Locked() {
+		if lockedOperators[state.Key] {
+			continue
+		}
+
+		lockouts = append(lockouts, models.LoginLockout{
+			Scope:       "username",
+			Key:         state.Key,
+			Failures:    state.Failures,
+			LockedUntil: state.LockedUntil,
+		})
+	}
+
+	for _, state := range h.ipThrottle.Locked() {
+		lockouts = append(lockouts, models.LoginLockout{
+			Scope:       "ip",
+			Key:         state.Key,
+			Failures:    state.Failures,
+			LockedUntil: state.LockedUntil,
+		})
+	}
+
+	if lockouts == nil {
+		lockouts = []models.LoginLockout{}
+	}
+
+	helpers.SetResponse(w, r, "Request successful", lockouts, http.StatusOK)
+}
+
+// UnlockOperatorHandler clears the lockout and failed login counter of a username.
+func (h *Handler) UnlockOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	operator, err := h.operators.GetOperatorByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.usernameThrottle.Reset(strings.ToLower(username))
+			helpers.SetResponse(w, r, "Lockout cleared", nil, http.StatusOK)
+			return
+		}
+
+		logger.Println(r.Context(), "Error fetching operator:", err)
+		helpers.SetResponse(w, r, "Failed to clear lockout", nil, http.StatusInternalServerError)
+		return
+	}
+
+	if admin, _ := auth.OperatorFromContext(r.Context()); !auth.CanGrantRole(admin, operator.Role) {
+		helpers.SetResponse(w, r, "You do not have permission to unlock this operator", nil, http.StatusForbidden)
+		return
+	}
+
+	if err := h.operators.ResetOperatorLoginFailures(r.Context(), operator.ID); err != nil {
+		logger.Println(r.Context(), "Error resetting login failures:", err)
+		helpers.SetResponse(w, r, "Failed to clear lockout", nil, http.StatusInternalServerError)
+		return
+	}
+
+	h.usernameThrottle.Reset(strings.ToLower(username))
+
+	helpers.SetResponse(w, r, "Lockout cleared", nil, http.StatusOK)
+}
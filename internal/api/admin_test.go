@@ -0,0 +1,129 @@
+package api_test
+
+import (
+	"attendance-app/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const lockoutsPath = "/api/v1/admin/lockouts"
+
+func TestLockoutsListsLockedOperators(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	lockedUntil := time.Now().Add(time.Minute)
+	if err := s.store.RecordOperatorLoginFailure(context.Background(), operator.ID, &lockedUntil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(t, http.MethodGet, lockoutsPath, s.token(t, admin), nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var lockouts []models.LoginLockout
+	if err := json.Unmarshal(decode(t, w).Data, &lockouts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lockouts) != 1 || lockouts[0].Scope != "operator" || lockouts[0].Key != "gate1" {
+		t.Errorf("lockouts = %+v, want the operator gate1", lockouts)
+	}
+}
+
+func TestUnlockOperatorClearsLockout(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	// Use up the free attempts so the username backs off
+	for i := 0; i < 4; i++ {
+		expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", "Wrong1234", "device-1")), http.StatusUnauthorized)
+	}
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusTooManyRequests)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	if err := s.store.RecordOperatorLoginFailure(context.Background(), operator.ID, &lockedUntil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, s.do(t, http.MethodDelete, lockoutsPath+"/gate1", s.token(t, admin), nil), http.StatusOK)
+
+	stored, err := s.store.GetOperatorByUsername(context.Background(), "gate1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FailedLoginAttempts != 0 || stored.LockedUntil != nil {
+		t.Errorf("failed_login_attempts = %d, locked_until = %v after unlock", stored.FailedLoginAttempts, stored.LockedUntil)
+	}
+
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusOK)
+}
+
+func TestUnlockUnknownUsername(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+
+	expectStatus(t, s.do(t, http.MethodDelete, lockoutsPath+"/nobody", s.token(t, admin), nil), http.StatusOK)
+}
+
+func TestRoutesRequirePermission(t *testing.T) {
+	routes := []struct {
+		method, path string
+		allowed      []string
+	}{
+		// The scanner is operator 1, the supervisor only syncs for itself
+		{http.MethodGet, "/api/v1/sync/1", []string{"scanner", "event_admin", "super_admin"}},
+		{http.MethodGet, lockoutsPath, []string{"supervisor", "event_admin", "super_admin"}},
+		{http.MethodDelete, lockoutsPath + "/nobody", []string{"event_admin", "super_admin"}},
+		{http.MethodPost, "/api/v1/admin/operators", []string{"event_admin", "super_admin"}},
+		{http.MethodPost, "/api/v1/admin/operators/1/password-reset", []string{"super_admin"}},
+		{http.MethodGet, "/api/v1/tickets/T-1/signed", []string{"super_admin"}},
+	}
+
+	s := newTestServer(t)
+
+	tokens := map[string]string{}
+	for _, role := range []string{"scanner", "supervisor", "event_admin", "super_admin"} {
+		tokens[role] = s.token(t, s.addOperator(t, role, role))
+	}
+
+	for _, route := range routes {
+		allowed := map[string]bool{}
+		for _, role := range route.allowed {
+			allowed[role] = true
+		}
+
+		for role, token := range tokens {
+			w := s.do(t, route.method, route.path, token, nil)
+
+			if denied := w.Code == http.StatusForbidden; denied == allowed[role] {
+				t.Errorf("%s %s as %s: status %d", route.method, route.path, role, w.Code)
+			}
+		}
+	}
+}
+
+func TestEventAdminCannotUnlockSuperAdmin(t *testing.T) {
+	s := newTestServer(t)
+	eventAdmin := s.addOperator(t, "events", "event_admin")
+	superAdmin := s.addOperator(t, "root", "super_admin")
+
+	lockedUntil := time.Now().Add(time.Minute)
+	if err := s.store.RecordOperatorLoginFailure(context.Background(), superAdmin.ID, &lockedUntil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, s.do(t, http.MethodDelete, lockoutsPath+"/root", s.token(t, eventAdmin), nil), http.StatusForbidden)
+
+	stored, err := s.store.GetOperatorByUsername(context.Background(), "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LockedUntil == nil {
+		t.Error("the super admin was unlocked")
+	}
+}
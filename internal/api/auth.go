@@ -1,32 +1,73 @@
 package api
 
 import (
+	"attendance-app/internal/auth"
 	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/metrics"
 	"attendance-app/internal/models"
-	"attendance-app/internal/repository"
+	"context"
+	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"os"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator"
-	"github.com/golang-jwt/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecretKey = []byte(os.Getenv("JWT_SECRET"))
-
 var validate = validator.New()
 
+func init() {
+	validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return auth.ValidatePassword(fl.Field().String()) == nil
+	})
+}
+
+// Limits of the login throttles per username and per client IP. The IP limits
+// are looser because the scanners at a venue usually share one network.
+var (
+	usernameThrottleConfig = auth.ThrottleConfig{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		ResetAfter:       time.Hour,
+	}
+	ipThrottleConfig = auth.ThrottleConfig{
+		FreeAttempts:     20,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  15 * time.Minute,
+		ResetAfter:       time.Hour,
+	}
+)
+
+// Reasons recorded in the login audit log
+const (
+	loginReasonThrottled       = "throttled"
+	loginReasonLocked          = "locked"
+	loginReasonUnknownUser     = "unknown_user"
+	loginReasonDeviceMismatch  = "device_mismatch"
+	loginReasonInvalidPassword = "invalid_password"
+	loginReasonInactive        = "inactive"
+	loginReasonOldPassword     = "invalid_old_password"
+	loginReasonResetCode       = "invalid_reset_code"
+)
+
 type LoginRequest struct {
 	UserName string  `json:"username" validate:"required,max=255"`
 	Password string  `json:"password" validate:"required,max=255"`
 	DeviceId *string `json:"device_id" validate:"required"`
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		helpers.SetResponse(w, r, "Invalid request method", nil, http.StatusBadRequest)
 		return
@@ -42,27 +83,54 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate the request body
-	validate := validator.New()
-
-	err := validate.Struct(loginReq)
-	if err != nil {
+	if err := validate.Struct(loginReq); err != nil {
 		helpers.SetResponse(w, r, "Validation Failed", err, http.StatusUnprocessableEntity)
 		return
 	}
 
+	ip := helpers.ClientIP(r)
+	usernameKey := strings.ToLower(loginReq.UserName)
+
+	// Refuse early while the username or IP is backing off or locked out
+	if retryAfter, ok := h.ipThrottle.Allow(ip); !ok {
+		h.rejectThrottledLogin(w, r, loginReq.UserName, ip, retryAfter)
+		return
+	}
+
+	if retryAfter, ok := h.usernameThrottle.Allow(usernameKey); !ok {
+		h.rejectThrottledLogin(w, r, loginReq.UserName, ip, retryAfter)
+		return
+	}
+
 	// Check if operator exists
-	storedOperator, err := repository.GetOperatorByUsername(loginReq.UserName)
+	storedOperator, err := h.operators.GetOperatorByUsername(r.Context(), loginReq.UserName)
 
-	if err != nil {
-		log.Printf("Authentication error: %v", err)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Printf(r.Context(), "Login for unknown username: %s", loginReq.UserName)
+		h.recordLoginFailure(r, loginReq.UserName, ip, nil, loginReasonUnknownUser)
 		helpers.SetResponse(w, r, "Invalid username or password", nil, http.StatusUnauthorized)
 		return
 	}
 
-	// Compare device id to prevent multi login user
-	print("storedOperator.DeviceId", loginReq.DeviceId)
+	// The lookup failing says nothing about the credentials, so it does not
+	// count against the username or IP
+	if err != nil {
+		logger.Printf(r.Context(), "Authentication error: %v", err)
+		helpers.SetResponse(w, r, "Failed to log in", nil, http.StatusInternalServerError)
+		return
+	}
+
+	// Refuse accounts locked by an earlier run of failed attempts
+	if storedOperator.LockedUntil != nil && storedOperator.LockedUntil.After(time.Now()) {
+		h.writeLoginAttempt(r, loginReq.UserName, ip, &storedOperator.ID, loginReasonLocked)
+		w.Header().Set("Retry-After", retryAfterSeconds(time.Until(*storedOperator.LockedUntil)))
+		helpers.SetResponse(w, r, "Too many failed login attempts. Your account is temporarily locked.", nil, http.StatusLocked)
+		return
+	}
 
-	if *loginReq.DeviceId != *storedOperator.DeviceId {
+	// Compare device id to prevent multi login user
+	if storedOperator.DeviceId != nil && *loginReq.DeviceId != *storedOperator.DeviceId {
+		h.writeLoginAttempt(r, loginReq.UserName, ip, &storedOperator.ID, loginReasonDeviceMismatch)
 		helpers.SetResponse(w, r, "You have logged in from another device. Please contact the administrator for further assistance.", nil, http.StatusForbidden)
 		return
 	}
@@ -71,26 +139,35 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	err = bcrypt.CompareHashAndPassword([]byte(storedOperator.Password), []byte(loginReq.Password))
 
 	if err != nil {
-		log.Printf("Password mismatch for username: %s", loginReq.UserName)
+		logger.Printf(r.Context(), "Password mismatch for username: %s", loginReq.UserName)
+		h.recordLoginFailure(r, loginReq.UserName, ip, &storedOperator.ID, loginReasonInvalidPassword)
 		helpers.SetResponse(w, r, "Invalid username or password", nil, http.StatusUnauthorized)
 		return
 	}
 
-	// Generate a JWT token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": storedOperator.UserName,
-		"id":       storedOperator.ID,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	})
+	// Deactivated operators keep their credentials but may not sign in
+	if !storedOperator.IsActive {
+		h.writeLoginAttempt(r, loginReq.UserName, ip, &storedOperator.ID, loginReasonInactive)
+		helpers.SetResponse(w, r, "Your account is inactive. Please contact the administrator for further assistance.", nil, http.StatusForbidden)
+		return
+	}
 
-	jwtSecretKey := os.Getenv("JWT_SECRET_KEY")
-	if jwtSecretKey == "" {
-		log.Fatal("JWT_SECRET_KEY environment variable is not set")
+	// Forget earlier failures once the operator gets in
+	h.usernameThrottle.Reset(usernameKey)
+
+	if storedOperator.FailedLoginAttempts > 0 || storedOperator.LockedUntil != nil {
+		if err := h.operators.ResetOperatorLoginFailures(r.Context(), storedOperator.ID); err != nil {
+			logger.Println(r.Context(), "Error resetting login failures:", err)
+		}
+
+		storedOperator.FailedLoginAttempts = 0
+		storedOperator.LockedUntil = nil
 	}
 
-	tokenString, err := token.SignedString([]byte(jwtSecretKey))
+	// Generate a JWT token
+	tokenString, err := h.tokens.GenerateToken(storedOperator)
 	if err != nil {
-		log.Printf("JWT signing error: %v", err)
+		logger.Printf(r.Context(), "JWT signing error: %v", err)
 		helpers.SetResponse(w, r, "Could not create JWT token", nil, http.StatusInternalServerError)
 		return
 	}
@@ -98,14 +175,18 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	storedOperator.DeviceId = loginReq.DeviceId
 	storedOperator.DeviceAccessToken = &tokenString
 
+	// The password hash never leaves the server
+	storedOperator.Password = ""
+
 	// Respond with the JWT token
 	response := models.LoginResponseData{
-		Token: tokenString,
-		User:  *storedOperator,
+		Token:       tokenString,
+		User:        *storedOperator,
+		Permissions: auth.PermissionNames(storedOperator),
 	}
 
-	if err := repository.UpdateOperatorDeviceInformation(storedOperator.DeviceId, storedOperator.DeviceAccessToken, storedOperator.ID); err != nil {
-		log.Println("Error", err)
+	if err := h.operators.UpdateOperatorDeviceInformation(r.Context(), storedOperator.DeviceId, storedOperator.DeviceAccessToken, storedOperator.ID); err != nil {
+		logger.Println(r.Context(), "Error", err)
 		helpers.SetResponse(w, r, "Failed to update device information", nil, http.StatusInternalServerError)
 		return
 	}
@@ -113,6 +194,58 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	helpers.SetResponse(w, r, "Login Success!", response, http.StatusOK)
 }
 
+// recordLoginFailure counts a failed login against the username and IP, locks
+// the operator account when the threshold is reached and writes the audit log.
+func (h *Handler) recordLoginFailure(r *http.Request, username, ip string, operatorID *int, reason string) {
+	h.ipThrottle.Failure(ip)
+	_, lockedUntil := h.usernameThrottle.Failure(strings.ToLower(username))
+
+	if operatorID != nil {
+		var lock *time.Time
+		if !lockedUntil.IsZero() {
+			lock = &lockedUntil
+			logger.Printf(r.Context(), "Operator %s locked until %s", username, lockedUntil.Format(time.RFC3339))
+		}
+
+		// A client hanging up must not stop the failure from counting
+		ctx := context.WithoutCancel(r.Context())
+
+		if err := h.operators.RecordOperatorLoginFailure(ctx, *operatorID, lock); err != nil {
+			logger.Println(r.Context(), "Error recording login failure:", err)
+		}
+	}
+
+	h.writeLoginAttempt(r, username, ip, operatorID, reason)
+}
+
+// rejectThrottledLogin answers a login attempt made while backing off.
+func (h *Handler) rejectThrottledLogin(w http.ResponseWriter, r *http.Request, username, ip string, retryAfter time.Duration) {
+	h.writeLoginAttempt(r, username, ip, nil, loginReasonThrottled)
+	w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+	helpers.SetResponse(w, r, "Too many login attempts. Please try again later.", nil, http.StatusTooManyRequests)
+}
+
+func (h *Handler) writeLoginAttempt(r *http.Request, username, ip string, operatorID *int, reason string) {
+	metrics.LoginFailures.WithLabelValues(reason).Inc()
+
+	attempt := models.LoginAttempt{
+		OperatorID: operatorID,
+		UserName:   username,
+		IPAddress:  ip,
+		UserAgent:  r.UserAgent(),
+		Reason:     reason,
+	}
+
+	if err := h.operators.InsertLoginAttempt(context.WithoutCancel(r.Context()), attempt); err != nil {
+		logger.Println(r.Context(), "Error writing login audit log:", err)
+	}
+}
+
+func retryAfterSeconds(d time.Duration) string {
+	// Round up so clients never retry a moment too early
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
+
 // isValidEmail validates the email format
 func isValidEmail(email string) bool {
 	regex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
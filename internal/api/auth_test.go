@@ -0,0 +1,207 @@
+package api_test
+
+import (
+	"attendance-app/internal/models"
+	"attendance-app/internal/repository/memory"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const loginPath = "/api/v1/operator/login"
+
+func loginBody(username, password, deviceID string) map[string]string {
+	return map[string]string{
+		"username":  username,
+		"password":  password,
+		"device_id": deviceID,
+	}
+}
+
+func TestLoginSucceeds(t *testing.T) {
+	s := newTestServer(t)
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1"))
+	expectStatus(t, w, http.StatusOK)
+
+	var data struct {
+		Token       string                 `json:"token"`
+		User        map[string]interface{} `json:"user"`
+		Permissions []string               `json:"permissions"`
+	}
+	if err := json.Unmarshal(decode(t, w).Data, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := s.tokens.ParseToken(data.Token)
+	if err != nil {
+		t.Fatalf("login returned an invalid token: %v", err)
+	}
+	if claims.ID != operator.ID {
+		t.Errorf("token is for operator %d, want %d", claims.ID, operator.ID)
+	}
+
+	if _, ok := data.User["password"]; ok {
+		t.Error("login response contains the password hash")
+	}
+
+	if len(data.Permissions) == 0 {
+		t.Error("login response has no permissions")
+	}
+
+	// The device is bound to the operator
+	stored, err := s.store.GetOperatorByUsername(context.Background(), "gate1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.DeviceId == nil || *stored.DeviceId != "device-1" {
+		t.Errorf("device_id = %v, want device-1", stored.DeviceId)
+	}
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	s := newTestServer(t)
+	s.addOperator(t, "gate1", "scanner")
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", "Wrong1234", "device-1"))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	if strings.Contains(w.Body.String(), "token") {
+		t.Errorf("failed login returned a token: %s", w.Body.String())
+	}
+
+	stored, err := s.store.GetOperatorByUsername(context.Background(), "gate1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FailedLoginAttempts != 1 {
+		t.Errorf("failed_login_attempts = %d, want 1", stored.FailedLoginAttempts)
+	}
+
+	attempts := s.store.LoginAttempts()
+	if len(attempts) != 1 || attempts[0].Reason != "invalid_password" {
+		t.Errorf("login attempts = %+v, want one invalid_password", attempts)
+	}
+}
+
+func TestLoginRejectsUnknownUser(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("nobody", testPassword, "device-1"))
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// Unknown users get the same answer as a wrong password
+	if got := decode(t, w).Message; got != "Invalid username or password" {
+		t.Errorf("message = %q", got)
+	}
+}
+
+// failingOperators is a store whose username lookups fail, as when the
+// database is unreachable.
+type failingOperators struct {
+	*memory.Store
+}
+
+func (failingOperators) GetOperatorByUsername(ctx context.Context, username string) (*models.Operator, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestLoginLookupErrorIsNotAFailure(t *testing.T) {
+	store := memory.NewStore()
+	s := newTestServerWith(t, store, failingOperators{store})
+
+	// More attempts than the username may fail before backing off
+	for i := 0; i < 5; i++ {
+		expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusInternalServerError)
+	}
+
+	if attempts := store.LoginAttempts(); len(attempts) != 0 {
+		t.Errorf("login attempts = %+v, want none", attempts)
+	}
+}
+
+func TestLoginThrottleIgnoresSpoofedForwardedFor(t *testing.T) {
+	s := newTestServer(t)
+
+	login := func(i int) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(loginBody(fmt.Sprintf("nobody%d", i), testPassword, "device-1"))
+
+		r := httptest.NewRequest(http.MethodPost, loginPath, bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// Past the free attempts of the IP every failure backs it off, whatever
+	// address the client claims to be forwarded for
+	for i := 0; i <= 20; i++ {
+		expectStatus(t, login(i), http.StatusUnauthorized)
+	}
+
+	expectStatus(t, login(21), http.StatusTooManyRequests)
+}
+
+func TestLoginRejectsInactiveOperator(t *testing.T) {
+	s := newTestServer(t)
+	s.addOperator(t, "gate1", "scanner", func(o *models.Operator) {
+		o.IsActive = false
+	})
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1"))
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestLoginRejectsLockedOperator(t *testing.T) {
+	s := newTestServer(t)
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	lockedUntil := time.Now().Add(time.Minute)
+	if err := s.store.RecordOperatorLoginFailure(context.Background(), operator.ID, &lockedUntil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1"))
+	expectStatus(t, w, http.StatusLocked)
+
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("locked response has no Retry-After header")
+	}
+}
+
+func TestLoginRejectsOtherDevice(t *testing.T) {
+	s := newTestServer(t)
+	s.addOperator(t, "gate1", "scanner")
+
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusOK)
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-2")), http.StatusForbidden)
+}
+
+func TestLoginValidatesBody(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.do(t, http.MethodPost, loginPath, "", map[string]string{"username": "gate1"})
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+
+	var errs map[string][]string
+	if err := json.Unmarshal(decode(t, w).Errors, &errs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"password", "device_id"} {
+		if len(errs[field]) == 0 {
+			t.Errorf("no validation error for %s: %v", field, errs)
+		}
+	}
+}
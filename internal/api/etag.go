@@ -0,0 +1,46 @@
+package api
+
+import (
+	"attendance-app/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// attendanceETag names a version of an operator's download. It is weak, as
+// the body also carries the response ID and may be compressed differently.
+// The parts describe anything else that changes the body, such as the
+// published verification key.
+func attendanceETag(version models.AttendanceVersion, parts ...string) string {
+	updatedAt := ""
+	if version.UpdatedAt != nil {
+		updatedAt = version.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d\n%s\n%s", version.Tickets, updatedAt, strings.Join(parts, "\n"))
+
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether If-None-Match names the ETag, comparing weakly
+// as RFC 9110 requires for GET.
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
+	"attendance-app/internal/repository"
+	"attendance-app/internal/tickets"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Handler holds the dependencies shared by the API handlers.
+type Handler struct {
+	operators      repository.OperatorStore
+	attendance     repository.AttendanceStore
+	tokens         *auth.TokenIssuer
+	tickets        *tickets.Signer // nil when ticket signing is not configured
+	ticketValidity time.Duration
+	sync           config.SyncConfig
+	readiness      []ReadinessCheck
+
+	// Login throttles per username and per client IP
+	usernameThrottle *auth.Throttle
+	ipThrottle       *auth.Throttle
+}
+
+// NewHandler creates the API handlers from the loaded configuration and the
+// stores they read and write.
+func NewHandler(cfg *config.Config, tokens *auth.TokenIssuer, operators repository.OperatorStore, attendance repository.AttendanceStore) (*Handler, error) {
+	h := &Handler{
+		operators:        operators,
+		attendance:       attendance,
+		tokens:           tokens,
+		ticketValidity:   cfg.Tickets.Validity,
+		sync:             cfg.Sync,
+		usernameThrottle: auth.NewThrottle(usernameThrottleConfig),
+		ipThrottle:       auth.NewThrottle(ipThrottleConfig),
+	}
+
+	if cfg.Tickets.SigningKey != nil {
+		signer, err := tickets.NewSigner(cfg.Tickets.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+
+		h.tickets = signer
+	} else {
+		log.Println("TICKET_SIGNING_KEY is not set, signed tickets are disabled")
+	}
+
+	h.AddReadinessCheck("config", h.checkConfig)
+
+	return h, nil
+}
+
+// checkConfig reports whether the settings the handlers run with are usable:
+// tokens can be signed, uploads are allowed a body and a batch, and signed
+// tickets, when enabled, stay valid for a while.
+func (h *Handler) checkConfig(ctx context.Context) error {
+	if h.tokens == nil {
+		return errors.New("token issuer is not set")
+	}
+
+	if err := h.tokens.Check(); err != nil {
+		return err
+	}
+
+	if h.sync.MaxBodyBytes <= 0 || h.sync.MaxBatchSize <= 0 {
+		return fmt.Errorf("sync limits must be positive, got %d bytes and %d tickets", h.sync.MaxBodyBytes, h.sync.MaxBatchSize)
+	}
+
+	if h.tickets != nil && h.ticketValidity <= 0 {
+		return errors.New("ticket validity must be positive")
+	}
+
+	return nil
+}
+
+// databaseErrorStatus answers 504 when a query ran out of time and 500 for
+// any other database error.
+func databaseErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/migrations"
+	"attendance-app/internal/version"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Each readiness check must answer within this time
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck reports whether a dependency is ready to serve traffic.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// AddReadinessCheck registers an extra check for /readyz.
+func (h *Handler) AddReadinessCheck(name string, check func(ctx context.Context) error) {
+	h.readiness = append(h.readiness, ReadinessCheck{Name: name, Check: check})
+}
+
+// AddDatabaseChecks registers the checks of the database behind the stores:
+// that it answers, and that its schema is at the version this build expects,
+// which fails after a rollback of the database.
+func (h *Handler) AddDatabaseChecks(pool *pgxpool.Pool) {
+	h.AddReadinessCheck("database", pool.Ping)
+	h.AddReadinessCheck("migrations", func(ctx context.Context) error {
+		return migrations.Check(ctx, pool)
+	})
+}
+
+// HealthzHandler reports that the process is alive. It does not touch any
+// dependency so a slow database does not get the process restarted.
+func (h *Handler) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	helpers.SetResponse(w, r, "OK", map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+// ReadyzHandler runs the readiness checks and answers 503 when any fails, so
+// the load balancer stops sending traffic. The endpoint is public, so why a
+// check failed is only logged.
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	response := ReadinessResponse{
+		Status: "ok",
+		Checks: make(map[string]string, len(h.readiness)),
+	}
+
+	for _, check := range h.readiness {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := check.Check(ctx)
+		cancel()
+
+		if err != nil {
+			logger.Printf(r.Context(), "Readiness check %s failed: %v", check.Name, err)
+			response.Status = "unavailable"
+			response.Checks[check.Name] = "unavailable"
+			continue
+		}
+
+		response.Checks[check.Name] = "ok"
+	}
+
+	if response.Status != "ok" {
+		helpers.SetResponse(w, r, "Service unavailable", response, http.StatusServiceUnavailable)
+		return
+	}
+
+	helpers.SetResponse(w, r, "Ready", response, http.StatusOK)
+}
+
+// VersionHandler reports the commit and time the running binary was built from.
+func (h *Handler) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	helpers.SetResponse(w, r, "Request successful", version.Get(), http.StatusOK)
+}
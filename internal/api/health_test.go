@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"attendance-app/internal/api"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
+	"attendance-app/internal/repository/memory"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyz(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+
+	w := s.do(t, http.MethodGet, "/readyz", "", nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var readiness api.ReadinessResponse
+	if err := json.Unmarshal(decode(t, w).Data, &readiness); err != nil {
+		t.Fatal(err)
+	}
+
+	if readiness.Status != "ok" || readiness.Checks["database"] != "ok" {
+		t.Errorf("readiness = %+v", readiness)
+	}
+}
+
+func TestReadyzHidesFailureDetails(t *testing.T) {
+	s := newTestServer(t)
+	s.handler.AddReadinessCheck("database", func(ctx context.Context) error {
+		return errors.New(`failed to connect to host=10.0.0.5 user=attendance: password authentication failed`)
+	})
+
+	w := s.do(t, http.MethodGet, "/readyz", "", nil)
+	expectStatus(t, w, http.StatusServiceUnavailable)
+
+	if body := w.Body.String(); strings.Contains(body, "10.0.0.5") || strings.Contains(body, "password") {
+		t.Errorf("readiness response exposes the error: %s", body)
+	}
+
+	var readiness api.ReadinessResponse
+	if err := json.Unmarshal(decode(t, w).Errors, &readiness); err != nil {
+		t.Fatal(err)
+	}
+
+	if readiness.Status != "unavailable" || readiness.Checks["database"] != "unavailable" || readiness.Checks["config"] != "ok" {
+		t.Errorf("readiness = %+v", readiness)
+	}
+}
+
+func TestReadyzChecksConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+	}{
+		{"no JWT secret", config.Config{
+			Auth: config.AuthConfig{TokenLifetime: time.Hour},
+			Sync: config.SyncConfig{MaxBodyBytes: 1024, MaxBatchSize: 10},
+		}},
+		{"no upload batch", config.Config{
+			Auth: config.AuthConfig{JWTSecret: "test-secret-that-is-at-least-32-characters", TokenLifetime: time.Hour},
+			Sync: config.SyncConfig{MaxBodyBytes: 1024},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStore()
+
+			h, err := api.NewHandler(&tt.cfg, auth.NewTokenIssuer(tt.cfg.Auth), store, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			h.ReadyzHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			expectStatus(t, w, http.StatusServiceUnavailable)
+
+			var readiness api.ReadinessResponse
+			if err := json.Unmarshal(decode(t, w).Errors, &readiness); err != nil {
+				t.Fatal(err)
+			}
+
+			if readiness.Checks["config"] != "unavailable" {
+				t.Errorf("readiness = %+v", readiness)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package api_test
+
+import (
+	"attendance-app/internal/api"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
+	"attendance-app/internal/models"
+	"attendance-app/internal/repository"
+	"attendance-app/internal/repository/memory"
+	"attendance-app/routes"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testPassword = "Secret123"
+
+	// Sync limits of the test server
+	testMaxBodyBytes = 64 << 10
+	testMaxBatchSize = 100
+)
+
+// Roles every test server has, by name, with their IDs
+var testRoles = map[string]int{
+	"scanner":     1,
+	"supervisor":  2,
+	"event_admin": 3,
+	"super_admin": 4,
+}
+
+// testServer is the full router backed by an in-memory store.
+type testServer struct {
+	store   *memory.Store
+	tokens  *auth.TokenIssuer
+	handler *api.Handler
+	router  http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	store := memory.NewStore()
+
+	return newTestServerWith(t, store, store)
+}
+
+// newTestServerWith serves operators from the given store, which may wrap
+// the memory store to make some of its calls fail.
+func newTestServerWith(t *testing.T, store *memory.Store, operators repository.OperatorStore) *testServer {
+	t.Helper()
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			JWTSecret:     "test-secret-that-is-at-least-32-characters",
+			TokenLifetime: time.Hour,
+		},
+		Tickets: config.TicketConfig{Validity: time.Hour},
+		Sync:    config.SyncConfig{MaxBodyBytes: testMaxBodyBytes, MaxBatchSize: testMaxBatchSize},
+	}
+
+	for name, id := range testRoles {
+		store.AddRole(models.Role{ID: &id, Name: &name})
+	}
+
+	tokens := auth.NewTokenIssuer(cfg.Auth)
+
+	h, err := api.NewHandler(cfg, tokens, operators, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{
+		store:   store,
+		tokens:  tokens,
+		handler: h,
+		router:  routes.SetupRoutes(cfg, h, tokens, operators),
+	}
+}
+
+// addOperator stores an active operator with the given role and testPassword.
+// The options change the operator before it is stored.
+func (s *testServer) addOperator(t *testing.T, username, role string, options ...func(*models.Operator)) models.Operator {
+	t.Helper()
+
+	// The minimum cost keeps the tests fast, login accepts any cost
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roleID, ok := testRoles[role]
+	if !ok {
+		t.Fatalf("unknown test role %q", role)
+	}
+
+	operator := models.Operator{
+		Role:     models.Role{ID: &roleID, Name: &role},
+		Name:     username,
+		UserName: username,
+		Phone:    "0812345678",
+		IsActive: true,
+	}
+
+	for _, option := range options {
+		option(&operator)
+	}
+
+	return s.store.AddOperator(operator, string(hash))
+}
+
+// token logs the operator in without going through the login endpoint.
+func (s *testServer) token(t *testing.T, operator models.Operator) string {
+	t.Helper()
+
+	token, err := s.tokens.GenerateToken(&operator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// do sends a request with a JSON body, when given, and an optional token.
+func (s *testServer) do(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest(method, path, &payload)
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+// response is the envelope every endpoint answers with.
+type response struct {
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Errors  json.RawMessage `json:"errors"`
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) response {
+	t.Helper()
+
+	var resp response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response is not JSON: %v\n%s", err, w.Body.String())
+	}
+
+	return resp
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d\n%s", w.Code, want, w.Body.String())
+	}
+}
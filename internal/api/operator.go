@@ -0,0 +1,193 @@
+package api
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/models"
+	"attendance-app/internal/repository"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{8,20}$`)
+
+type RegisterOperatorRequest struct {
+	Name                 string  `json:"name"`
+	UserName             string  `json:"username"`
+	Email                *string `json:"email"`
+	Phone                string  `json:"phone"`
+	Password             string  `json:"password"`
+	PasswordConfirmation string  `json:"password_confirmation"`
+	RoleID               int     `json:"role_id"`
+	ClassIDs             []int   `json:"class_ids"`
+}
+
+// RegisterOperatorHandler lets an admin create an operator with a role and
+// access to a set of classes.
+func (h *Handler) RegisterOperatorHandler(w http.ResponseWriter, r *http.Request) {
+	admin, _ := auth.OperatorFromContext(r.Context())
+
+	var req RegisterOperatorRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helpers.SetResponse(w, r, "Invalid request body", nil, http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.UserName = strings.TrimSpace(req.UserName)
+	req.Phone = strings.TrimSpace(req.Phone)
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		req.Email = &email
+
+		if email == "" {
+			req.Email = nil
+		}
+	}
+
+	validationErrors := validateRegisterRequest(req)
+
+	// The role must exist and may not grant more than the admin holds
+	var role *models.Role
+
+	if req.RoleID != 0 {
+		var err error
+
+		role, err = h.operators.GetRole(r.Context(), req.RoleID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Println(r.Context(), "Error fetching role:", err)
+				helpers.SetResponse(w, r, "Failed to register operator", nil, http.StatusInternalServerError)
+				return
+			}
+
+			validationErrors.RoleError = "role_id does not exist."
+		} else if !auth.CanGrantRole(admin, *role) {
+			validationErrors.RoleError = "You cannot assign a role with more permissions than your own."
+		}
+	}
+
+	if validationErrors.HasErrors() {
+		helpers.SetResponse(w, r, "Validation Failed", validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Reject duplicate username, email or phone
+	usernameTaken, emailTaken, phoneTaken, err := h.operators.OperatorExists(r.Context(), req.UserName, req.Email, req.Phone)
+	if err != nil {
+		logger.Println(r.Context(), "Error checking operator existence:", err)
+		helpers.SetResponse(w, r, "Failed to register operator", nil, http.StatusInternalServerError)
+		return
+	}
+
+	if usernameTaken {
+		validationErrors.UserNameError = "username has already been taken."
+	}
+	if emailTaken {
+		validationErrors.EmailError = "email has already been taken."
+	}
+	if phoneTaken {
+		validationErrors.PhoneError = "phone has already been taken."
+	}
+
+	if validationErrors.HasErrors() {
+		helpers.SetResponse(w, r, "Validation Failed", validationErrors, http.StatusUnprocessableEntity)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		logger.Println(r.Context(), "Error hashing password:", err)
+		helpers.SetResponse(w, r, "Failed to register operator", nil, http.StatusInternalServerError)
+		return
+	}
+
+	operator := models.Operator{
+		Role:     *role,
+		Name:     req.Name,
+		UserName: req.UserName,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		IsActive: true,
+	}
+
+	if _, err := h.operators.CreateOperator(r.Context(), &operator, hashedPassword, req.ClassIDs); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrOperatorExists):
+			// Lost a race with another registration
+			helpers.SetResponse(w, r, "Validation Failed", models.RegisterValidationErrors{
+				UserNameError: "username, email or phone has already been taken.",
+			}, http.StatusUnprocessableEntity)
+		case errors.Is(err, repository.ErrClassNotFound):
+			helpers.SetResponse(w, r, "Validation Failed", models.RegisterValidationErrors{
+				ClassesError: "One or more classes do not exist.",
+			}, http.StatusUnprocessableEntity)
+		default:
+			logger.Println(r.Context(), "Error creating operator:", err)
+			helpers.SetResponse(w, r, "Failed to register operator", nil, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	helpers.SetResponse(w, r, "Operator registered", operator, http.StatusCreated)
+}
+
+// validateRegisterRequest checks the fields that do not need the database.
+func validateRegisterRequest(req RegisterOperatorRequest) models.RegisterValidationErrors {
+	var errs models.RegisterValidationErrors
+
+	switch {
+	case req.Name == "":
+		errs.NameError = "name is required."
+	case len(req.Name) > 255:
+		errs.NameError = "name cannot exceed 255 characters."
+	}
+
+	switch {
+	case req.UserName == "":
+		errs.UserNameError = "username is required."
+	case len(req.UserName) > 255:
+		errs.UserNameError = "username cannot exceed 255 characters."
+	case strings.ContainsAny(req.UserName, " \t\n"):
+		errs.UserNameError = "username cannot contain spaces."
+	}
+
+	if req.Email != nil {
+		switch {
+		case len(*req.Email) > 255:
+			errs.EmailError = "email cannot exceed 255 characters."
+		case !isValidEmail(*req.Email):
+			errs.EmailError = "email must be a valid email address."
+		}
+	}
+
+	switch {
+	case req.Phone == "":
+		errs.PhoneError = "phone is required."
+	case !phonePattern.MatchString(req.Phone):
+		errs.PhoneError = "phone must be 8 to 20 digits."
+	}
+
+	if req.Password == "" {
+		errs.PasswordError = "password is required."
+	} else if err := auth.ValidatePassword(req.Password); err != nil {
+		errs.PasswordError = "password " + err.Error() + "."
+	}
+
+	if req.PasswordConfirmation != req.Password {
+		errs.PasswordConfirmationError = "password_confirmation does not match password."
+	}
+
+	if req.RoleID == 0 {
+		errs.RoleError = "role_id is required."
+	}
+
+	return errs
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"attendance-app/internal/models"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+const registerPath = "/api/v1/admin/operators"
+
+func registerBody(username string, roleID int, classIDs ...int) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                  "Gate " + username,
+		"username":              username,
+		"email":                 username + "@example.com",
+		"phone":                 "081234567890",
+		"password":              testPassword,
+		"password_confirmation": testPassword,
+		"role_id":               roleID,
+		"class_ids":             classIDs,
+	}
+}
+
+func TestRegisterOperatorThenLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.store.AddClass(models.Class{ID: 10, Name: "VIP", Event: models.Event{ID: 1, Name: "Concert"}})
+	admin := s.token(t, s.addOperator(t, "admin", "super_admin"))
+
+	expectStatus(t, s.do(t, http.MethodPost, registerPath, admin, registerBody("gate9", testRoles["scanner"], 10)), http.StatusCreated)
+
+	w := s.do(t, http.MethodPost, loginPath, "", loginBody("gate9", testPassword, "device-9"))
+	expectStatus(t, w, http.StatusOK)
+
+	var data models.LoginResponseData
+	if err := json.Unmarshal(decode(t, w).Data, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.User.Role.Name == nil || *data.User.Role.Name != "scanner" {
+		t.Errorf("role = %v, want scanner", data.User.Role.Name)
+	}
+
+	if events := data.User.AccessEvents; len(events) != 1 || events[0].Name != "Concert" ||
+		len(events[0].Classes) != 1 || events[0].Classes[0].ID != 10 {
+		t.Errorf("access_events = %+v, want class 10 of Concert", events)
+	}
+}
+
+func TestRegisterOperatorValidates(t *testing.T) {
+	scanner := testRoles["scanner"]
+
+	weak := registerBody("gate9", scanner)
+	weak["password"], weak["password_confirmation"] = "lowercase1", "lowercase1"
+
+	short := registerBody("gate9", scanner)
+	short["password"], short["password_confirmation"] = "Ab1", "Ab1"
+
+	mismatch := registerBody("gate9", scanner)
+	mismatch["password_confirmation"] = "Other1234"
+
+	takenPhone := registerBody("gate9", scanner)
+	takenPhone["phone"] = "0812345678"
+
+	takenUsername := registerBody("admin", scanner)
+	takenUsername["email"], takenUsername["phone"] = "other@example.com", "0877777777"
+
+	tests := []struct {
+		name    string
+		body    map[string]interface{}
+		field   string
+		message string
+	}{
+		// The same rules and messages as a password change
+		{"weak password", weak, "password", "password must contain upper case and lower case letters and a number."},
+		{"short password", short, "password", "password must be at least 8 characters."},
+		{"confirmation mismatch", mismatch, "password_confirmation_error", "password_confirmation does not match password."},
+		{"duplicate phone", takenPhone, "phone", "phone has already been taken."},
+		{"duplicate username", takenUsername, "username", "username has already been taken."},
+		{"unknown role", registerBody("gate9", 99), "role_id", "role_id does not exist."},
+		{"unknown class", registerBody("gate9", scanner, 77), "class_ids", "One or more classes do not exist."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			admin := s.token(t, s.addOperator(t, "admin", "super_admin"))
+
+			w := s.do(t, http.MethodPost, registerPath, admin, test.body)
+			expectStatus(t, w, http.StatusUnprocessableEntity)
+
+			var errs map[string]string
+			if err := json.Unmarshal(decode(t, w).Errors, &errs); err != nil {
+				t.Fatal(err)
+			}
+			if errs[test.field] != test.message {
+				t.Errorf("%s error = %q, want %q (%v)", test.field, errs[test.field], test.message, errs)
+			}
+		})
+	}
+}
+
+func TestPasswordRulesAreShared(t *testing.T) {
+	s := newTestServer(t)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, changePasswordPath, token, changePasswordBody(testPassword, "lowercase1", "lowercase1"))
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+
+	var errs map[string][]string
+	if err := json.Unmarshal(decode(t, w).Errors, &errs); err != nil {
+		t.Fatal(err)
+	}
+	if want := "password must contain upper case and lower case letters and a number."; len(errs["password"]) != 1 || errs["password"][0] != want {
+		t.Errorf("password errors = %v, want %q", errs["password"], want)
+	}
+}
+
+func TestEventAdminCannotRegisterSuperAdmin(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.token(t, s.addOperator(t, "events", "event_admin"))
+
+	w := s.do(t, http.MethodPost, registerPath, admin, registerBody("root2", testRoles["super_admin"]))
+	expectStatus(t, w, http.StatusUnprocessableEntity)
+
+	var errs map[string]string
+	if err := json.Unmarshal(decode(t, w).Errors, &errs); err != nil {
+		t.Fatal(err)
+	}
+	if errs["role_id"] == "" {
+		t.Errorf("no role_id error: %v", errs)
+	}
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reset codes issued by an admin are valid for one hour
+const passwordResetLifetime = time.Hour
+
+type ChangePasswordRequest struct {
+	OldPassword          string `json:"old_password" validate:"required,max=255"`
+	Password             string `json:"password" validate:"required,password,nefield=OldPassword"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+type ResetPasswordRequest struct {
+	UserName             string `json:"username" validate:"required,max=255"`
+	Code                 string `json:"code" validate:"required,max=255"`
+	Password             string `json:"password" validate:"required,password"`
+	PasswordConfirmation string `json:"password_confirmation" validate:"required,eqfield=Password"`
+}
+
+// ChangePasswordHandler lets the logged in operator change their own password.
+// All existing sessions, including the current one, are revoked.
+func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	operator, _ := auth.OperatorFromContext(r.Context())
+
+	var req ChangePasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helpers.SetResponse(w, r, "Invalid request body", nil, http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		helpers.SetResponse(w, r, "Validation Failed", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	ip := helpers.ClientIP(r)
+
+	// Guessing the old password with a stolen token is throttled like a login
+	if retryAfter, ok := h.usernameThrottle.Allow(strings.ToLower(operator.UserName)); !ok {
+		h.rejectThrottledLogin(w, r, operator.UserName, ip, retryAfter)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(operator.Password), []byte(req.OldPassword)); err != nil {
+		h.recordLoginFailure(r, operator.UserName, ip, &operator.ID, loginReasonOldPassword)
+		helpers.SetResponse(w, r, "Validation Failed", map[string][]string{
+			"old_password": {"old_password is incorrect."},
+		}, http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !h.updatePassword(w, r, operator.ID, req.Password) {
+		return
+	}
+
+	helpers.SetResponse(w, r, "Password changed. Please log in again.", nil, http.StatusOK)
+}
+
+// AdminResetPasswordHandler issues a one-time code the operator can use to
+// choose a new password. The code is only shown in this response. Admins may
+// only reset operators whose role is below their own.
+func (h *Handler) AdminResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	admin, _ := auth.OperatorFromContext(r.Context())
+
+	operatorId, err := strconv.Atoi(mux.Vars(r)["operatorId"])
+	if err != nil {
+		helpers.SetResponse(w, r, "Invalid operator id", nil, http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.operators.GetOperatorByID(r.Context(), operatorId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			helpers.SetResponse(w, r, "Operator not found", nil, http.StatusNotFound)
+			return
+		}
+
+		logger.Println(r.Context(), "Error fetching operator:", err)
+		helpers.SetResponse(w, r, "Failed to reset password", nil, http.StatusInternalServerError)
+		return
+	}
+
+	if !auth.OutranksRole(admin, target.Role) {
+		helpers.SetResponse(w, r, "You do not have permission to reset the password of this operator", nil, http.StatusForbidden)
+		return
+	}
+
+	code, err := auth.GenerateResetCode()
+	if err != nil {
+		logger.Println(r.Context(), "Error generating reset code:", err)
+		helpers.SetResponse(w, r, "Failed to reset password", nil, http.StatusInternalServerError)
+		return
+	}
+
+	codeHash, err := auth.HashPassword(code)
+	if err != nil {
+		logger.Println(r.Context(), "Error hashing reset code:", err)
+		helpers.SetResponse(w, r, "Failed to reset password", nil, http.StatusInternalServerError)
+		return
+	}
+
+	expiresAt := time.Now().Add(passwordResetLifetime)
+
+	if err := h.operators.SetOperatorPasswordResetCode(r.Context(), operatorId, codeHash, expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			helpers.SetResponse(w, r, "Operator not found", nil, http.StatusNotFound)
+			return
+		}
+
+		logger.Println(r.Context(), "Error storing reset code:", err)
+		helpers.SetResponse(w, r, "Failed to reset password", nil, http.StatusInternalServerError)
+		return
+	}
+
+	response := models.PasswordResetCode{
+		OperatorID: operatorId,
+		Code:       code,
+		ExpiresAt:  expiresAt,
+	}
+
+	helpers.SetResponse(w, r, "Password reset code issued", response, http.StatusOK)
+}
+
+// ResetPasswordHandler sets a new password using a code issued by an admin.
+// A successful reset also clears any login lockout.
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helpers.SetResponse(w, r, "Invalid request body", nil, http.StatusBadRequest)
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		helpers.SetResponse(w, r, "Validation Failed", err, http.StatusUnprocessableEntity)
+		return
+	}
+
+	ip := helpers.ClientIP(r)
+	usernameKey := strings.ToLower(req.UserName)
+
+	// Reset codes are short, so guesses share the login throttles
+	if retryAfter, ok := h.ipThrottle.Allow(ip); !ok {
+		h.rejectThrottledLogin(w, r, req.UserName, ip, retryAfter)
+		return
+	}
+
+	if retryAfter, ok := h.usernameThrottle.Allow(usernameKey); !ok {
+		h.rejectThrottledLogin(w, r, req.UserName, ip, retryAfter)
+		return
+	}
+
+	reset, err := h.operators.GetOperatorPasswordReset(r.Context(), req.UserName)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Println(r.Context(), "Error fetching password reset:", err)
+			helpers.SetResponse(w, r, "Failed to reset password", nil, http.StatusInternalServerError)
+			return
+		}
+
+		h.recordLoginFailure(r, req.UserName, ip, nil, loginReasonUnknownUser)
+		helpers.SetResponse(w, r, "Invalid or expired reset code", nil, http.StatusBadRequest)
+		return
+	}
+
+	if reset.CodeHash == nil || reset.ExpiresAt == nil || time.Now().After(*reset.ExpiresAt) ||
+		bcrypt.CompareHashAndPassword([]byte(*reset.CodeHash), []byte(strings.ToUpper(req.Code))) != nil {
+		h.recordLoginFailure(r, req.UserName, ip, &reset.OperatorID, loginReasonResetCode)
+		helpers.SetResponse(w, r, "Invalid or expired reset code", nil, http.StatusBadRequest)
+		return
+	}
+
+	if !h.updatePassword(w, r, reset.OperatorID, req.Password) {
+		return
+	}
+
+	h.usernameThrottle.Reset(usernameKey)
+
+	if err := h.operators.ResetOperatorLoginFailures(r.Context(), reset.OperatorID); err != nil {
+		logger.Println(r.Context(), "Error resetting login failures:", err)
+	}
+
+	helpers.SetResponse(w, r, "Password has been reset. Please log in with your new password.", nil, http.StatusOK)
+}
+
+// updatePassword hashes and stores the new password, writing an error
+// response and returning false when it fails.
+func (h *Handler) updatePassword(w http.ResponseWriter, r *http.Request, operatorID int, password string) bool {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		logger.Println(r.Context(), "Error hashing password:", err)
+		helpers.SetResponse(w, r, "Failed to update password", nil, http.StatusInternalServerError)
+		return false
+	}
+
+	if err := h.operators.UpdateOperatorPassword(r.Context(), operatorID, hashedPassword); err != nil {
+		logger.Println(r.Context(), "Error updating password:", err)
+		helpers.SetResponse(w, r, "Failed to update password", nil, http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
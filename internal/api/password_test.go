@@ -0,0 +1,155 @@
+package api_test
+
+import (
+	"attendance-app/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const (
+	changePasswordPath = "/api/v1/operator/password"
+	resetPasswordPath  = "/api/v1/operator/password/reset"
+	newPassword        = "Changed456"
+)
+
+func changePasswordBody(oldPassword, password, confirmation string) map[string]string {
+	return map[string]string{
+		"old_password":          oldPassword,
+		"password":              password,
+		"password_confirmation": confirmation,
+	}
+}
+
+// expectSession checks whether the token is still accepted.
+func expectSession(t *testing.T, s *testServer, token string, valid bool) {
+	t.Helper()
+
+	w := s.do(t, http.MethodGet, "/api/v1/sync/0", token, nil)
+	if revoked := w.Code == http.StatusUnauthorized; revoked == valid {
+		t.Errorf("session valid = %t, want %t (status %d)", !revoked, valid, w.Code)
+	}
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	s := newTestServer(t)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, changePasswordPath, token, changePasswordBody(testPassword, newPassword, newPassword))
+	expectStatus(t, w, http.StatusOK)
+
+	expectSession(t, s, token, false)
+
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusUnauthorized)
+
+	// A session started right after the change, within the same second, is valid
+	w = s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", newPassword, "device-1"))
+	expectStatus(t, w, http.StatusOK)
+
+	var data models.LoginResponseData
+	if err := json.Unmarshal(decode(t, w).Data, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	expectSession(t, s, data.Token, true)
+}
+
+func TestChangePasswordValidates(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  map[string]string
+		field string
+	}{
+		{"wrong old password", changePasswordBody("Wrong1234", newPassword, newPassword), "old_password"},
+		{"weak password", changePasswordBody(testPassword, "lowercase1", "lowercase1"), "password"},
+		{"short password", changePasswordBody(testPassword, "Ab1", "Ab1"), "password"},
+		{"same password", changePasswordBody(testPassword, testPassword, testPassword), "password"},
+		{"confirmation mismatch", changePasswordBody(testPassword, newPassword, "Changed789"), "password_confirmation"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestServer(t)
+			token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+			w := s.do(t, http.MethodPut, changePasswordPath, token, test.body)
+			expectStatus(t, w, http.StatusUnprocessableEntity)
+
+			var errs map[string][]string
+			if err := json.Unmarshal(decode(t, w).Errors, &errs); err != nil {
+				t.Fatal(err)
+			}
+			if len(errs[test.field]) == 0 {
+				t.Errorf("no validation error for %s: %v", test.field, errs)
+			}
+
+			// The password is unchanged
+			expectSession(t, s, token, true)
+		})
+	}
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+	operator := s.addOperator(t, "gate1", "scanner")
+	operatorToken := s.token(t, operator)
+
+	w := s.do(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/operators/%d/password-reset", operator.ID), s.token(t, admin), nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var code models.PasswordResetCode
+	if err := json.Unmarshal(decode(t, w).Data, &code); err != nil {
+		t.Fatal(err)
+	}
+
+	reset := map[string]string{
+		"username":              "gate1",
+		"code":                  code.Code,
+		"password":              newPassword,
+		"password_confirmation": newPassword,
+	}
+
+	expectStatus(t, s.do(t, http.MethodPost, resetPasswordPath, "", reset), http.StatusOK)
+
+	expectSession(t, s, operatorToken, false)
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", newPassword, "device-1")), http.StatusOK)
+
+	// The code is used up
+	expectStatus(t, s.do(t, http.MethodPost, resetPasswordPath, "", reset), http.StatusBadRequest)
+}
+
+func TestAdminResetPasswordRejectsWrongCode(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	expectStatus(t, s.do(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/operators/%d/password-reset", operator.ID), s.token(t, admin), nil), http.StatusOK)
+
+	w := s.do(t, http.MethodPost, resetPasswordPath, "", map[string]string{
+		"username":              "gate1",
+		"code":                  "WRONGCOD",
+		"password":              newPassword,
+		"password_confirmation": newPassword,
+	})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	expectStatus(t, s.do(t, http.MethodPost, loginPath, "", loginBody("gate1", testPassword, "device-1")), http.StatusOK)
+}
+
+func TestAdminResetPasswordRequiresHigherRole(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+	other := s.addOperator(t, "root", "super_admin")
+
+	w := s.do(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/operators/%d/password-reset", other.ID), s.token(t, admin), nil)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestAdminResetPasswordUnknownOperator(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.addOperator(t, "admin", "super_admin")
+
+	expectStatus(t, s.do(t, http.MethodPost, "/api/v1/admin/operators/99/password-reset", s.token(t, admin), nil), http.StatusNotFound)
+}
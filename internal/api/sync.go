@@ -1,11 +1,15 @@
 package api
 
 import (
+	"attendance-app/internal/auth"
 	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/metrics"
 	"attendance-app/internal/models"
 	"attendance-app/internal/repository"
-	"encoding/json"
-	"log"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,26 +17,76 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Formats of a sync download, chosen with the format query parameter
+const (
+	syncFormatFlat       = "flat"       // A list of SyncResponse, the default
+	syncFormatNormalized = "normalized" // A models.SyncDownload
+)
+
 // SyncHandler handles the GET /sync/{operatorId} request.
-func SyncHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SyncHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract operatorId from URL
 	vars := mux.Vars(r)
 	operatorId, _ := strconv.Atoi(vars["operatorId"])
-	log.Println("Operator ID", operatorId)
+	logger.Println(r.Context(), "Operator ID", operatorId)
 
-	// Query the database for the attendance data related to this operator
-	data, err := repository.GetAttendanceData(operatorId) // Use the repository package function
+	// Scanners only download the tickets of their own classes
+	operator, ok := auth.OperatorFromContext(r.Context())
+	if !ok || !auth.CanActFor(operator, operatorId) {
+		helpers.SetResponse(w, r, "You do not have permission to sync for this operator", nil, http.StatusForbidden)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = syncFormatFlat
+	}
+
+	if format != syncFormatFlat && format != syncFormatNormalized {
+		helpers.SetResponse(w, r, "Unknown format, use flat or normalized", nil, http.StatusBadRequest)
+		return
+	}
+
+	// Publish the key scanners use to verify signed tickets they have not synced
+	var meta models.SyncMeta
+	var keyID string
+
+	if h.tickets != nil {
+		key := h.tickets.VerificationKey()
+		meta.TicketVerificationKey = &key
+		keyID = key.KeyID
+	}
 
+	// Read the version before the data, so a change in between makes the
+	// scanner download again rather than miss it
+	version, err := h.attendance.GetAttendanceVersion(r.Context(), operatorId)
 	if err != nil {
-		var message string
+		syncError(w, r, err)
+		return
+	}
 
-		message = "Failed to get data"
+	etag := attendanceETag(version, keyID, format, helpers.ResponseContentType(r))
 
-		if err.Error() == "operator_not_found" {
-			message = "Operator not found"
-		}
+	// Scanners revalidate every time instead of trusting a stale copy
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
 
-		helpers.SetResponse(w, r, message, nil, http.StatusNotFound)
+	if etagMatches(r, etag) {
+		helpers.SetNotModified(w, r)
+		return
+	}
+
+	// Query the database for the attendance data related to this operator
+	data, err := h.attendance.GetAttendanceData(r.Context(), operatorId)
+	if err != nil {
+		syncError(w, r, err)
+		return
+	}
+
+	localizeAttendTimes(r.Context(), data)
+
+	if format == syncFormatNormalized {
+		helpers.SetResponseWithMeta(w, r, "Request successful", models.NormalizeSync(data), meta, http.StatusOK)
 		return
 	}
 
@@ -41,16 +95,47 @@ func SyncHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Respond with the fetched data
-	helpers.SetResponse(w, r, "Request successful", data, http.StatusOK)
+	helpers.SetResponseWithMeta(w, r, "Request successful", data, meta, http.StatusOK)
+}
+
+// syncError answers a failed sync download: 404 for an unknown operator, and
+// 500 or 504 for a failing database.
+func syncError(w http.ResponseWriter, r *http.Request, err error) {
+	// The ETag belongs to the data, not to this error
+	w.Header().Del("ETag")
+
+	if errors.Is(err, repository.ErrOperatorNotFound) {
+		helpers.SetResponse(w, r, "Operator not found", nil, http.StatusNotFound)
+		return
+	}
+
+	logger.Println(r.Context(), "Error", err)
+
+	message := "Failed to get data"
+	if errors.Is(err, context.DeadlineExceeded) {
+		message = "Database timed out"
+	}
+
+	helpers.SetResponse(w, r, message, nil, databaseErrorStatus(err))
 }
-func SyncPutHandler(w http.ResponseWriter, r *http.Request) {
+
+func (h *Handler) SyncPutHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse the request payload
 	var payload models.SyncPayload
 
-	// Decode the JSON payload into the SyncPayload struct
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		helpers.SetResponse(w, r, "Invalid request payload", nil, http.StatusBadRequest)
-		log.Println(err)
+	// Decode the JSON or MessagePack payload into the SyncPayload struct,
+	// refusing bodies past the limit and fields the app does not know
+	r.Body = http.MaxBytesReader(w, r.Body, h.sync.MaxBodyBytes)
+
+	if err := helpers.DecodeBody(r, &payload); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			helpers.SetResponse(w, r, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit), nil, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		helpers.SetResponse(w, r, "Invalid request payload", err.Error(), http.StatusBadRequest)
+		logger.Println(r.Context(), err)
 		return
 	}
 
@@ -60,47 +145,77 @@ func SyncPutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Extract invoice codes from the data
-	var invoiceCodes []string
-	for _, item := range payload.Data {
-		invoiceCodes = append(invoiceCodes, item.InvoiceCode)
+	if len(payload.Data) > h.sync.MaxBatchSize {
+		metrics.SyncRejectedTickets.WithLabelValues("batch_too_large").Add(float64(len(payload.Data)))
+		helpers.SetResponse(w, r, fmt.Sprintf("Upload has %d tickets, at most %d are accepted at once", len(payload.Data), h.sync.MaxBatchSize), nil, http.StatusRequestEntityTooLarge)
+		return
 	}
 
-	// Check if all invoice codes exist in the database
-	exists, err := repository.CheckInvoicesExist(invoiceCodes)
+	metrics.SyncBatchSize.Observe(float64(len(payload.Data)))
 
-	if err != nil {
-		helpers.SetResponse(w, r, "Database error", err.Error(), http.StatusInternalServerError)
-		return
-	}
+	// Transform SyncData into SyncDataUpdate. Times with an offset are exact,
+	// those without one are wall-clock times at the event, resolved below.
+	updates := make([]models.SyncDataUpdate, len(payload.Data))
+	var local []int
 
-	if !exists {
-		helpers.SetResponse(w, r, "One or more invoice codes do not exist", nil, http.StatusBadRequest)
-		return
+	for i, item := range payload.Data {
+		updates[i].InvoiceCode = item.InvoiceCode
+
+		if attendTime, ok := parseOffsetTime(item.AttendTime); ok {
+			updates[i].AttendTime = attendTime.UTC()
+			continue
+		}
+
+		if _, err := parseLocalTime(item.AttendTime, time.UTC); err != nil {
+			metrics.SyncRejectedTickets.WithLabelValues("invalid_attend_time").Add(float64(len(payload.Data)))
+			helpers.SetResponse(w, r, "Invalid time format for attend_time, use RFC 3339 such as 2006-01-02T15:04:05+07:00", nil, http.StatusBadRequest)
+			logger.Println(r.Context(), "Error parsing AttendTime:", err)
+			return
+		}
+
+		local = append(local, i)
 	}
 
-	// Transform SyncData into SyncDataUpdate
-	var updates []models.SyncDataUpdate
-	for _, item := range payload.Data {
-		layout := "2006-01-02 15:04:05" // Expected format of time string
-		attendTime, err := time.Parse(layout, item.AttendTime)
+	if len(local) > 0 {
+		codes := make([]string, len(local))
+		for j, i := range local {
+			codes[j] = updates[i].InvoiceCode
+		}
+
+		zones, err := h.attendance.GetTicketTimeZones(r.Context(), codes)
 		if err != nil {
-			helpers.SetResponse(w, r, "Invalid time format for attend_time", nil, http.StatusBadRequest)
-			log.Println("Error parsing AttendTime:", err)
+			logger.Println(r.Context(), "Error", err)
+			helpers.SetResponse(w, r, "Failed to update attendance status", nil, databaseErrorStatus(err))
 			return
 		}
-		updates = append(updates, models.SyncDataUpdate{
-			InvoiceCode: item.InvoiceCode,
-			AttendTime:  attendTime,
-		})
+
+		// Unknown tickets keep UTC, the update rejects them anyway
+		for _, i := range local {
+			attendTime, _ := parseLocalTime(payload.Data[i].AttendTime, eventLocation(r.Context(), zones[updates[i].InvoiceCode]))
+			updates[i].AttendTime = attendTime.UTC()
+		}
 	}
 
-	// Update attendance status
-	if err := repository.UpdateAttendanceStatus(updates, 1); err != nil {
-		log.Println("Error", err)
-		helpers.SetResponse(w, r, "Failed to update attendance status", nil, http.StatusInternalServerError)
+	// The tickets are checked and updated in one transaction, recording who
+	// uploaded them
+	operator, _ := auth.OperatorFromContext(r.Context())
+	err := h.attendance.UpdateAttendanceStatus(r.Context(), updates, operator.ID)
+
+	// Uploads are all or nothing, so every ticket in the batch is rejected
+	if errors.Is(err, repository.ErrTicketNotFound) {
+		metrics.SyncRejectedTickets.WithLabelValues("unknown_ticket").Add(float64(len(payload.Data)))
+		helpers.SetResponse(w, r, "One or more invoice codes do not exist", nil, http.StatusBadRequest)
 		return
 	}
 
+	if err != nil {
+		logger.Println(r.Context(), "Error", err)
+		metrics.SyncRejectedTickets.WithLabelValues("update_failed").Add(float64(len(updates)))
+		helpers.SetResponse(w, r, "Failed to update attendance status", nil, databaseErrorStatus(err))
+		return
+	}
+
+	metrics.TicketsCheckedIn.Add(float64(len(updates)))
+
 	helpers.SetResponse(w, r, "Attendance status updated successfully", nil, http.StatusOK)
 }
@@ -0,0 +1,630 @@
+package api_test
+
+import (
+	"attendance-app/internal/api"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
+	"attendance-app/internal/models"
+	"attendance-app/internal/repository"
+	"attendance-app/internal/repository/memory"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// addTickets stores a class with two tickets, one of them already scanned,
+// that scanner 1 can sync, and a ticket of another class.
+func addTickets(s *testServer) {
+	event := models.Event{ID: 1, Name: "Concert"}
+	s.store.AddClass(models.Class{ID: 10, Name: "VIP", Event: event})
+	s.store.AddClass(models.Class{ID: 20, Name: "Regular", Event: event})
+	s.store.AddScanner(1, 10)
+
+	scannedAt := time.Date(2025, 1, 10, 18, 30, 0, 0, time.UTC)
+
+	s.store.AddTicket(memory.Ticket{ID: 1, InvoiceCode: "INV-1", TicketCode: "TCK-1", ClassID: 10,
+		User: models.User{ID: 1, Name: "Ana"}})
+	s.store.AddTicket(memory.Ticket{ID: 2, InvoiceCode: "INV-2", TicketCode: "TCK-2", ClassID: 10,
+		User: models.User{ID: 2, Name: "Budi"}, AttendStatus: true, AttendTime: &scannedAt})
+	s.store.AddTicket(memory.Ticket{ID: 3, InvoiceCode: "INV-3", TicketCode: "TCK-3", ClassID: 20,
+		User: models.User{ID: 3, Name: "Citra"}})
+}
+
+func TestSyncRequiresToken(t *testing.T) {
+	s := newTestServer(t)
+
+	expectStatus(t, s.do(t, http.MethodGet, "/api/v1/sync/1", "", nil), http.StatusUnauthorized)
+	expectStatus(t, s.do(t, http.MethodGet, "/api/v1/sync/1", "not-a-token", nil), http.StatusUnauthorized)
+}
+
+func TestSyncReturnsTicketsOfOperatorClasses(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodGet, "/api/v1/sync/1", token, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var tickets []models.SyncResponse
+	if err := json.Unmarshal(decode(t, w).Data, &tickets); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tickets) != 2 {
+		t.Fatalf("got %d tickets, want the 2 of class 10: %+v", len(tickets), tickets)
+	}
+
+	for _, ticket := range tickets {
+		if ticket.Class.ID != 10 || ticket.Class.Event.Name != "Concert" {
+			t.Errorf("ticket %s has class %+v, want VIP of Concert", ticket.TicketCode, ticket.Class)
+		}
+	}
+
+	if !tickets[1].AttendStatus || tickets[1].AttendTime == nil {
+		t.Errorf("scanned ticket lost its attendance: %+v", tickets[1])
+	}
+}
+
+func TestSyncOtherOperatorIsForbidden(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	s.store.AddScanner(2, 20)
+	s.addOperator(t, "gate1", "scanner")
+	token := s.token(t, s.addOperator(t, "gate2", "scanner"))
+
+	// gate2 asks for the tickets of gate1
+	w := s.do(t, http.MethodGet, "/api/v1/sync/1", token, nil)
+	expectStatus(t, w, http.StatusForbidden)
+
+	if body := w.Body.String(); strings.Contains(body, "TCK-1") || strings.Contains(body, "Ana") {
+		t.Errorf("forbidden response leaks tickets: %s", body)
+	}
+
+	expectStatus(t, s.do(t, http.MethodGet, "/api/v1/sync/2", token, nil), http.StatusOK)
+}
+
+func TestSyncUnknownOperator(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "admin", "super_admin"))
+
+	w := s.do(t, http.MethodGet, "/api/v1/sync/99", token, nil)
+	expectStatus(t, w, http.StatusNotFound)
+
+	if got := decode(t, w).Message; got != "Operator not found" {
+		t.Errorf("message = %q, want Operator not found", got)
+	}
+}
+
+func TestSyncUploadChecksInTickets(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	s.addOperator(t, "gate1", "scanner")
+	uploader := s.addOperator(t, "gate2", "scanner")
+	token := s.token(t, uploader)
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+	}})
+	expectStatus(t, w, http.StatusOK)
+
+	ticket, _ := s.store.Ticket("TCK-1")
+	if !ticket.AttendStatus || ticket.AttendTime == nil {
+		t.Fatalf("ticket was not checked in: %+v", ticket)
+	}
+
+	if want := time.Date(2025, 1, 10, 19, 0, 0, 0, time.UTC); !ticket.AttendTime.Equal(want) {
+		t.Errorf("attend_time = %s, want %s", ticket.AttendTime, want)
+	}
+
+	if ticket.AttendOperatorID == nil || *ticket.AttendOperatorID != uploader.ID {
+		t.Errorf("attend_operator_id = %v, want the uploader %d", ticket.AttendOperatorID, uploader.ID)
+	}
+}
+
+func TestSyncUploadKeepsEarliestCheckIn(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	// Scanned twice by this scanner, then uploaded late by another one
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:05:00"},
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+	}})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:30:00"},
+	}})
+	expectStatus(t, w, http.StatusOK)
+
+	ticket, _ := s.store.Ticket("TCK-1")
+	if want := time.Date(2025, 1, 10, 19, 0, 0, 0, time.UTC); ticket.AttendTime == nil || !ticket.AttendTime.Equal(want) {
+		t.Errorf("attend_time = %v, want %s", ticket.AttendTime, want)
+	}
+}
+
+func TestSyncUploadRejectsUnknownTicket(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+		{InvoiceCode: "TCK-404", AttendTime: "2025-01-10 19:00:00"},
+	}})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// Uploads are all or nothing
+	if ticket, _ := s.store.Ticket("TCK-1"); ticket.AttendStatus {
+		t.Error("known ticket was checked in although the batch was rejected")
+	}
+}
+
+func TestSyncUploadRejectsInvalidTime(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "yesterday"},
+	}})
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestSyncUploadRejectsEmptyBatch(t *testing.T) {
+	s := newTestServer(t)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	expectStatus(t, s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{}), http.StatusBadRequest)
+}
+
+func TestSyncUploadRejectsLargeBatch(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	payload := models.SyncPayload{}
+	for i := 0; i <= testMaxBatchSize; i++ {
+		payload.Data = append(payload.Data, models.SyncData{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"})
+	}
+
+	expectStatus(t, s.do(t, http.MethodPut, "/api/v1/sync", token, payload), http.StatusRequestEntityTooLarge)
+
+	if ticket, _ := s.store.Ticket("TCK-1"); ticket.AttendStatus {
+		t.Error("ticket was checked in although the batch was rejected")
+	}
+}
+
+func TestSyncUploadRejectsLargeBody(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: strings.Repeat("X", testMaxBodyBytes), AttendTime: "2025-01-10 19:00:00"},
+	}})
+	expectStatus(t, w, http.StatusRequestEntityTooLarge)
+}
+
+func TestSyncUploadRejectsUnknownFields(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, map[string]interface{}{
+		"data": []map[string]string{{"invoice_code": "TCK-1", "attend_time": "2025-01-10 19:00:00", "scanned_by": "gate1"}},
+	})
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestSyncDownloadIsCompressed(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			s := newTestServer(t)
+			addTickets(s)
+			token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/sync/1", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			r.Header.Set("Accept-Encoding", encoding)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+			expectStatus(t, w, http.StatusOK)
+
+			if got := w.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+			}
+
+			var body io.Reader
+			if encoding == "gzip" {
+				reader, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = reader
+			} else {
+				decoder, err := zstd.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer decoder.Close()
+				body = decoder
+			}
+
+			var envelope struct {
+				Data []models.SyncResponse `json:"data"`
+			}
+			if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(envelope.Data) != 2 {
+				t.Errorf("got %d tickets, want 2", len(envelope.Data))
+			}
+		})
+	}
+}
+
+func TestSyncUploadAcceptsCompressedBody(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd"} {
+		t.Run(encoding, func(t *testing.T) {
+			s := newTestServer(t)
+			addTickets(s)
+			token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+			var body bytes.Buffer
+			var encoder io.WriteCloser
+			if encoding == "gzip" {
+				encoder = gzip.NewWriter(&body)
+			} else {
+				encoder, _ = zstd.NewWriter(&body)
+			}
+
+			json.NewEncoder(encoder).Encode(models.SyncPayload{Data: []models.SyncData{
+				{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+			}})
+			encoder.Close()
+
+			r := httptest.NewRequest(http.MethodPut, "/api/v1/sync", &body)
+			r.Header.Set("Authorization", "Bearer "+token)
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Content-Encoding", encoding)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+			expectStatus(t, w, http.StatusOK)
+
+			if ticket, _ := s.store.Ticket("TCK-1"); !ticket.AttendStatus {
+				t.Error("ticket was not checked in")
+			}
+		})
+	}
+}
+
+func TestSyncUploadRejectsUnknownEncoding(t *testing.T) {
+	s := newTestServer(t)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/sync", strings.NewReader("{}"))
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("Content-Encoding", "br")
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	expectStatus(t, w, http.StatusUnsupportedMediaType)
+}
+
+func TestSyncDownloadAnswersNotModified(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/sync/1", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		if ifNoneMatch != "" {
+			r.Header.Set("If-None-Match", ifNoneMatch)
+		}
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := get("")
+	expectStatus(t, w, http.StatusOK)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("download has no ETag")
+	}
+
+	w = get(`"other", ` + etag)
+	expectStatus(t, w, http.StatusNotModified)
+	if w.Body.Len() != 0 {
+		t.Errorf("304 has a body: %s", w.Body)
+	}
+
+	// A check-in changes the data, so the old copy is stale
+	expectStatus(t, s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+	}}), http.StatusOK)
+
+	w = get(etag)
+	expectStatus(t, w, http.StatusOK)
+
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Errorf("ETag %s did not change after a check-in", got)
+	}
+}
+
+func TestSyncDownloadAsMessagePack(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/sync/1", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("Accept", "application/msgpack")
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	expectStatus(t, w, http.StatusOK)
+
+	if got := w.Header().Get("Content-Type"); got != "application/msgpack" {
+		t.Fatalf("Content-Type = %q, want application/msgpack", got)
+	}
+
+	var envelope struct {
+		Data []models.SyncResponse `json:"data"`
+	}
+
+	decoder := msgpack.NewDecoder(w.Body)
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(envelope.Data) != 2 {
+		t.Fatalf("got %d tickets, want 2", len(envelope.Data))
+	}
+
+	scanned := envelope.Data[1]
+	if want := time.Date(2025, 1, 10, 18, 30, 0, 0, time.UTC); scanned.AttendTime == nil || !scanned.AttendTime.Equal(want) {
+		t.Errorf("attend_time = %v, want %s", scanned.AttendTime, want)
+	}
+
+	if scanned.Class.Event.Name != "Concert" || scanned.User.Name != "Budi" {
+		t.Errorf("nested objects were lost: %+v", scanned)
+	}
+}
+
+func TestSyncUploadAsMessagePack(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	var body bytes.Buffer
+	encoder := msgpack.NewEncoder(&body)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-1", AttendTime: "2025-01-10 19:00:00"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/sync", &body)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("Content-Type", "application/msgpack")
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	expectStatus(t, w, http.StatusOK)
+
+	if ticket, _ := s.store.Ticket("TCK-1"); !ticket.AttendStatus {
+		t.Error("ticket was not checked in")
+	}
+}
+
+func TestSyncDownloadNormalized(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	s.store.AddTicket(memory.Ticket{ID: 4, InvoiceCode: "INV-1", TicketCode: "TCK-4", ClassID: 10,
+		User: models.User{ID: 1, Name: "Ana"}})
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodGet, "/api/v1/sync/1?format=normalized", token, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var download models.SyncDownload
+	if err := json.Unmarshal(decode(t, w).Data, &download); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(download.Events) != 1 || len(download.Classes) != 1 || len(download.Users) != 2 || len(download.Tickets) != 3 {
+		t.Fatalf("got %d events, %d classes, %d users and %d tickets, want 1, 1, 2 and 3: %+v",
+			len(download.Events), len(download.Classes), len(download.Users), len(download.Tickets), download)
+	}
+
+	if class := download.Classes[0]; class.ID != 10 || class.EventID != download.Events[0].ID {
+		t.Errorf("class = %+v, want class 10 of event %d", class, download.Events[0].ID)
+	}
+
+	for _, ticket := range download.Tickets {
+		if ticket.ClassID != 10 || (ticket.UserID != 1 && ticket.UserID != 2) {
+			t.Errorf("ticket %s refers to class %d and user %d", ticket.TicketCode, ticket.ClassID, ticket.UserID)
+		}
+	}
+
+	// The formats have different bodies, so they cannot share an ETag
+	flat := s.do(t, http.MethodGet, "/api/v1/sync/1", token, nil)
+	if flat.Header().Get("ETag") == w.Header().Get("ETag") {
+		t.Error("flat and normalized downloads have the same ETag")
+	}
+}
+
+func TestSyncDownloadUnknownFormat(t *testing.T) {
+	s := newTestServer(t)
+	addTickets(s)
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	expectStatus(t, s.do(t, http.MethodGet, "/api/v1/sync/1?format=xml", token, nil), http.StatusBadRequest)
+}
+
+func TestSyncAttendTimeUsesEventTimeZone(t *testing.T) {
+	s := newTestServer(t)
+	s.store.AddClass(models.Class{ID: 30, Name: "Festival", Event: models.Event{ID: 2, Name: "Jayapura Fest", TimeZone: "Asia/Jayapura"}})
+	s.store.AddScanner(1, 30)
+	s.store.AddTicket(memory.Ticket{ID: 5, InvoiceCode: "INV-5", TicketCode: "TCK-5", ClassID: 30, User: models.User{ID: 1, Name: "Ana"}})
+	s.store.AddTicket(memory.Ticket{ID: 6, InvoiceCode: "INV-6", TicketCode: "TCK-6", ClassID: 30, User: models.User{ID: 2, Name: "Budi"}})
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	// A wall-clock time at the event, and one from a scanner set to Jakarta time
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-5", AttendTime: "2025-01-10 19:00:00"},
+		{InvoiceCode: "TCK-6", AttendTime: "2025-01-10T17:00:00+07:00"},
+	}})
+	expectStatus(t, w, http.StatusOK)
+
+	want := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC)
+	for _, code := range []string{"TCK-5", "TCK-6"} {
+		ticket, _ := s.store.Ticket(code)
+		if ticket.AttendTime == nil || !ticket.AttendTime.Equal(want) || ticket.AttendTime.Location() != time.UTC {
+			t.Errorf("%s attend_time = %v, want %s", code, ticket.AttendTime, want)
+		}
+	}
+
+	// Downloads show the times at the event
+	w = s.do(t, http.MethodGet, "/api/v1/sync/1", token, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	var tickets []struct {
+		TicketCode string `json:"ticket_code"`
+		AttendTime string `json:"attend_time"`
+	}
+	if err := json.Unmarshal(decode(t, w).Data, &tickets); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ticket := range tickets {
+		if ticket.AttendTime != "2025-01-10T19:00:00+09:00" {
+			t.Errorf("%s attend_time = %s, want 2025-01-10T19:00:00+09:00", ticket.TicketCode, ticket.AttendTime)
+		}
+	}
+}
+
+func TestSyncDownloadAsMessagePackKeepsEventTimeZone(t *testing.T) {
+	s := newTestServer(t)
+	s.store.AddClass(models.Class{ID: 30, Name: "Festival", Event: models.Event{ID: 2, Name: "Jayapura Fest", TimeZone: "Asia/Jayapura"}})
+	s.store.AddScanner(1, 30)
+	s.store.AddTicket(memory.Ticket{ID: 5, InvoiceCode: "INV-5", TicketCode: "TCK-5", ClassID: 30, User: models.User{ID: 1, Name: "Ana"}})
+	token := s.token(t, s.addOperator(t, "gate1", "scanner"))
+
+	w := s.do(t, http.MethodPut, "/api/v1/sync", token, models.SyncPayload{Data: []models.SyncData{
+		{InvoiceCode: "TCK-5", AttendTime: "2025-01-10 19:00:00"},
+	}})
+	expectStatus(t, w, http.StatusOK)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/sync/1", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("Accept", "application/msgpack")
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	expectStatus(t, w, http.StatusOK)
+
+	// Read the raw value, as clients without our decoder do
+	var raw struct {
+		Data []struct {
+			AttendTime string `msgpack:"attend_time"`
+		} `msgpack:"data"`
+	}
+
+	if err := msgpack.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw.Data) != 1 || raw.Data[0].AttendTime != "2025-01-10T19:00:00+09:00" {
+		t.Fatalf("attend_time = %+v, want 2025-01-10T19:00:00+09:00", raw.Data)
+	}
+
+	// And decoded into the model, the offset survives
+	var envelope struct {
+		Data []models.SyncResponse `json:"data"`
+	}
+
+	decoder := msgpack.NewDecoder(w.Body)
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	attendTime := envelope.Data[0].AttendTime
+	if attendTime == nil {
+		t.Fatal("attend_time is missing")
+	}
+
+	if _, offset := attendTime.Zone(); offset != 9*60*60 || attendTime.Hour() != 19 {
+		t.Errorf("attend_time = %v, want 19:00 at +09:00", attendTime)
+	}
+}
+
+// failingAttendance is a store whose sync downloads fail with err.
+type failingAttendance struct {
+	*memory.Store
+	err error
+}
+
+func (f failingAttendance) GetAttendanceVersion(ctx context.Context, operatorID int) (models.AttendanceVersion, error) {
+	return models.AttendanceVersion{}, f.err
+}
+
+func TestSyncDatabaseErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{repository.ErrOperatorNotFound, http.StatusNotFound},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	s := newTestServer(t)
+	operator := s.addOperator(t, "gate1", "scanner")
+
+	for _, tt := range tests {
+		cfg := &config.Config{Sync: config.SyncConfig{MaxBodyBytes: testMaxBodyBytes, MaxBatchSize: testMaxBatchSize}}
+
+		h, err := api.NewHandler(cfg, s.tokens, s.store, failingAttendance{s.store, tt.err})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/sync/1", nil)
+		r = mux.SetURLVars(r, map[string]string{"operatorId": "1"})
+		r = r.WithContext(auth.WithOperator(r.Context(), &operator))
+
+		w := httptest.NewRecorder()
+		h.SyncHandler(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%v: status = %d, want %d", tt.err, w.Code, tt.status)
+		}
+	}
+}
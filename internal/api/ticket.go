@@ -0,0 +1,77 @@
+package api
+
+import (
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/models"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SignedTicketHandler issues a signed QR payload for a ticket. The optional
+// valid_from and valid_until query parameters (RFC 3339) set the window in
+// which scanners accept it, by default TICKET_VALIDITY from now.
+func (h *Handler) SignedTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if h.tickets == nil {
+		helpers.SetResponse(w, r, "Ticket signing is not configured", nil, http.StatusServiceUnavailable)
+		return
+	}
+
+	var err error
+
+	ticketCode := mux.Vars(r)["ticketCode"]
+
+	validFrom := time.Now()
+	if value := r.URL.Query().Get("valid_from"); value != "" {
+		if validFrom, err = time.Parse(time.RFC3339, value); err != nil {
+			helpers.SetResponse(w, r, "Invalid time format for valid_from", nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	validUntil := validFrom.Add(h.ticketValidity)
+	if value := r.URL.Query().Get("valid_until"); value != "" {
+		if validUntil, err = time.Parse(time.RFC3339, value); err != nil {
+			helpers.SetResponse(w, r, "Invalid time format for valid_until", nil, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !validUntil.After(validFrom) {
+		helpers.SetResponse(w, r, "valid_until must be after valid_from", nil, http.StatusBadRequest)
+		return
+	}
+
+	classID, err := h.attendance.GetTicketClassID(r.Context(), ticketCode)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			helpers.SetResponse(w, r, "Ticket not found", nil, http.StatusNotFound)
+			return
+		}
+
+		logger.Println(r.Context(), "Error fetching ticket:", err)
+		helpers.SetResponse(w, r, "Failed to get data", nil, http.StatusInternalServerError)
+		return
+	}
+
+	payload, err := h.tickets.Sign(ticketCode, classID, validFrom, validUntil)
+	if err != nil {
+		logger.Println(r.Context(), "Error signing ticket:", err)
+		helpers.SetResponse(w, r, "Failed to sign ticket", nil, http.StatusInternalServerError)
+		return
+	}
+
+	response := models.SignedTicket{
+		TicketCode: ticketCode,
+		ClassID:    classID,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+		Payload:    payload,
+	}
+
+	helpers.SetResponse(w, r, "Request successful", response, http.StatusOK)
+}
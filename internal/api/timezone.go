@@ -0,0 +1,70 @@
+package api
+
+import (
+	"attendance-app/internal/logger"
+	"attendance-app/internal/models"
+	"context"
+	"sync"
+	"time"
+	_ "time/tzdata" // Event time zones must resolve on hosts without a zone database
+)
+
+// Layouts of attend_time without an offset, read as wall-clock times at the
+// event. Older scanners send the first one.
+var localTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+// Loaded time zones by name
+var locations sync.Map
+
+// eventLocation returns the time zone of an event, UTC when it is not set or
+// not a zone Go knows.
+func eventLocation(ctx context.Context, name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+
+	if location, ok := locations.Load(name); ok {
+		return location.(*time.Location)
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		logger.Printf(ctx, "Unknown event time zone %q, showing times in UTC", name)
+		location = time.UTC
+	}
+
+	locations.Store(name, location)
+
+	return location
+}
+
+// parseOffsetTime reads an RFC 3339 attend_time, which carries its offset.
+func parseOffsetTime(value string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+// parseLocalTime reads an attend_time without an offset in the time zone of
+// the event.
+func parseLocalTime(value string, location *time.Location) (t time.Time, err error) {
+	for _, layout := range localTimeLayouts {
+		if t, err = time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return t, err
+}
+
+// localizeAttendTimes shows the attend times of a download in the time zone
+// of their event.
+func localizeAttendTimes(ctx context.Context, data []models.SyncResponse) {
+	for i := range data {
+		if data[i].AttendTime == nil {
+			continue
+		}
+
+		local := data[i].AttendTime.In(eventLocation(ctx, data[i].Class.Event.TimeZone))
+		data[i].AttendTime = &local
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Same cost as the Laravel admin panel hashes
+const passwordCost = 12
+
+// Reset codes avoid characters that are easy to misread
+const (
+	resetCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	resetCodeLength   = 8
+)
+
+// HashPassword hashes a password with bcrypt.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Length limits of a password
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 255
+)
+
+// ValidatePassword checks the rules every new password must meet, whether an
+// operator chooses it or an admin registers it, and describes the first one
+// it breaks.
+func ValidatePassword(password string) error {
+	switch {
+	case len(password) < MinPasswordLength:
+		return fmt.Errorf("must be at least %d characters", MinPasswordLength)
+	case len(password) > MaxPasswordLength:
+		return fmt.Errorf("cannot exceed %d characters", MaxPasswordLength)
+	case !isStrongPassword(password):
+		return errors.New("must contain upper case and lower case letters and a number")
+	}
+
+	return nil
+}
+
+// isStrongPassword requires upper and lower case letters and a digit.
+func isStrongPassword(password string) bool {
+	var hasUpper, hasLower, hasDigit bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+
+	return hasUpper && hasLower && hasDigit
+}
+
+// GenerateResetCode returns a random one-time code for a password reset.
+func GenerateResetCode() (string, error) {
+	code := make([]byte, resetCodeLength)
+	max := big.NewInt(int64(len(resetCodeAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = resetCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"attendance-app/internal/models"
+	"strings"
+)
+
+// Permission is an action an operator may be allowed to perform.
+type Permission string
+
+const (
+	PermissionSyncAttendance Permission = "attendance.sync"          // Download tickets and upload scans
+	PermissionViewLockouts   Permission = "operators.lockouts"       // See login lockouts
+	PermissionManageOperator Permission = "operators.manage"         // Create and manage operators
+	PermissionResetPassword  Permission = "operators.reset_password" // Issue password reset codes
+	PermissionIssueTickets   Permission = "tickets.issue"            // Issue signed ticket QR payloads
+)
+
+// Role names as stored in m_admin_attendance_roles, normalised by roleKey
+const (
+	RoleScanner    = "scanner"
+	RoleSupervisor = "supervisor"
+	RoleEventAdmin = "event_admin"
+	RoleSuperAdmin = "super_admin"
+)
+
+var allPermissions = []Permission{
+	PermissionSyncAttendance,
+	PermissionViewLockouts,
+	PermissionManageOperator,
+	PermissionResetPassword,
+	PermissionIssueTickets,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleScanner: {
+		PermissionSyncAttendance,
+	},
+	RoleSupervisor: {
+		PermissionSyncAttendance,
+		PermissionViewLockouts,
+	},
+	// Event admins run the scanners of an event. Password resets and ticket
+	// issuing stay with super admins, which also keeps event admins from
+	// creating or unlocking super admins, see CanGrantRole.
+	RoleEventAdmin: {
+		PermissionSyncAttendance,
+		PermissionViewLockouts,
+		PermissionManageOperator,
+	},
+	RoleSuperAdmin: allPermissions,
+}
+
+// PermissionsForRole returns the permissions granted to a role. Operators
+// without a known role keep the scanner permissions every operator had before
+// roles were enforced.
+func PermissionsForRole(role models.Role) []Permission {
+	if role.Name != nil {
+		if permissions, exists := rolePermissions[roleKey(*role.Name)]; exists {
+			return permissions
+		}
+	}
+
+	return rolePermissions[RoleScanner]
+}
+
+// PermissionNames returns the operator's permissions as strings for API responses.
+func PermissionNames(operator *models.Operator) []string {
+	permissions := PermissionsForRole(operator.Role)
+
+	names := make([]string, len(permissions))
+	for i, permission := range permissions {
+		names[i] = string(permission)
+	}
+
+	return names
+}
+
+// HasPermission reports whether the operator's role grants the permission.
+func HasPermission(operator *models.Operator, permission Permission) bool {
+	for _, granted := range PermissionsForRole(operator.Role) {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanGrantRole reports whether the operator holds every permission of the
+// role, so admins cannot create or manage operators more powerful than
+// themselves.
+func CanGrantRole(operator *models.Operator, role models.Role) bool {
+	for _, permission := range PermissionsForRole(role) {
+		if !HasPermission(operator, permission) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OutranksRole reports whether the operator holds every permission of the
+// role and more, as needed to act on the credentials of operators with it.
+func OutranksRole(operator *models.Operator, role models.Role) bool {
+	return CanGrantRole(operator, role) && len(PermissionsForRole(operator.Role)) > len(PermissionsForRole(role))
+}
+
+// CanActFor reports whether the operator may download or scan on behalf of
+// the operator with the ID. Operators act for themselves, and those who manage
+// operators act for any of them.
+func CanActFor(operator *models.Operator, operatorID int) bool {
+	return operator.ID == operatorID || HasPermission(operator, PermissionManageOperator)
+}
+
+// roleKey turns a role name such as "Event Admin" into "event_admin".
+func roleKey(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.ReplaceAll(key, "-", "_")
+	return strings.Join(strings.Fields(key), "_")
+}
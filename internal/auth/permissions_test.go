@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"attendance-app/internal/models"
+	"testing"
+)
+
+func role(name string) models.Role {
+	return models.Role{Name: &name}
+}
+
+func TestPermissionsForRole(t *testing.T) {
+	tests := []struct {
+		role    models.Role
+		granted []Permission
+		denied  []Permission
+	}{
+		{role("scanner"), []Permission{PermissionSyncAttendance}, []Permission{PermissionViewLockouts, PermissionManageOperator}},
+		{role("Supervisor"), []Permission{PermissionViewLockouts}, []Permission{PermissionManageOperator, PermissionResetPassword}},
+		{role("Event Admin"), []Permission{PermissionManageOperator}, []Permission{PermissionResetPassword, PermissionIssueTickets}},
+		{role("super-admin"), allPermissions, nil},
+		// Unknown and missing roles keep what every operator could do before
+		{role("cashier"), []Permission{PermissionSyncAttendance}, []Permission{PermissionViewLockouts}},
+		{models.Role{}, []Permission{PermissionSyncAttendance}, []Permission{PermissionViewLockouts}},
+	}
+
+	for _, test := range tests {
+		operator := &models.Operator{Role: test.role}
+
+		for _, permission := range test.granted {
+			if !HasPermission(operator, permission) {
+				t.Errorf("role %v lacks %s", test.role.Name, permission)
+			}
+		}
+
+		for _, permission := range test.denied {
+			if HasPermission(operator, permission) {
+				t.Errorf("role %v has %s", test.role.Name, permission)
+			}
+		}
+	}
+}
+
+func TestEventAdminIsNotSuperAdmin(t *testing.T) {
+	eventAdmin := &models.Operator{Role: role(RoleEventAdmin)}
+	superAdmin := &models.Operator{Role: role(RoleSuperAdmin)}
+
+	if CanGrantRole(eventAdmin, role(RoleSuperAdmin)) {
+		t.Error("an event admin can grant the super admin role")
+	}
+
+	for _, name := range []string{RoleScanner, RoleSupervisor, RoleEventAdmin} {
+		if !CanGrantRole(eventAdmin, role(name)) {
+			t.Errorf("an event admin cannot grant %s", name)
+		}
+	}
+
+	for _, name := range []string{RoleScanner, RoleSupervisor, RoleEventAdmin, RoleSuperAdmin} {
+		if !CanGrantRole(superAdmin, role(name)) {
+			t.Errorf("a super admin cannot grant %s", name)
+		}
+	}
+}
+
+func TestOutranksRole(t *testing.T) {
+	superAdmin := &models.Operator{Role: role(RoleSuperAdmin)}
+	eventAdmin := &models.Operator{Role: role(RoleEventAdmin)}
+
+	tests := []struct {
+		operator *models.Operator
+		role     string
+		want     bool
+	}{
+		{superAdmin, RoleScanner, true},
+		{superAdmin, RoleEventAdmin, true},
+		{superAdmin, RoleSuperAdmin, false},
+		{eventAdmin, RoleSupervisor, true},
+		{eventAdmin, RoleEventAdmin, false},
+		{eventAdmin, RoleSuperAdmin, false},
+	}
+
+	for _, test := range tests {
+		if got := OutranksRole(test.operator, role(test.role)); got != test.want {
+			t.Errorf("OutranksRole(%s, %s) = %t, want %t", *test.operator.Role.Name, test.role, got, test.want)
+		}
+	}
+}
+
+func TestCanActFor(t *testing.T) {
+	scanner := &models.Operator{ID: 1, Role: role(RoleScanner)}
+	admin := &models.Operator{ID: 2, Role: role(RoleEventAdmin)}
+
+	if !CanActFor(scanner, 1) || CanActFor(scanner, 2) {
+		t.Error("scanners act only for themselves")
+	}
+
+	if !CanActFor(admin, 1) || !CanActFor(admin, 2) {
+		t.Error("event admins act for any operator")
+	}
+}
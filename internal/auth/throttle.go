@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// ThrottleConfig controls how quickly repeated failures are slowed down and locked out.
+type ThrottleConfig struct {
+	FreeAttempts     int           // Failures allowed before backoff starts
+	BaseDelay        time.Duration // Delay after the first failure past FreeAttempts
+	MaxDelay         time.Duration // Upper bound for the exponential backoff
+	LockoutThreshold int           // Failures that trigger a temporary lockout
+	LockoutDuration  time.Duration // How long a lockout lasts
+	ResetAfter       time.Duration // Quiet period after which failures are forgotten
+}
+
+// Throttle tracks failed attempts per key (username, IP address) in memory.
+type Throttle struct {
+	mu      sync.Mutex
+	config  ThrottleConfig
+	entries map[string]*throttleEntry
+	now     func() time.Time
+}
+
+type throttleEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	lockedUntil  time.Time
+}
+
+// ThrottleState describes the current throttling state of a key.
+type ThrottleState struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Prune stale entries once the map grows beyond this size.
+const throttlePruneSize = 10000
+
+// NewThrottle creates a throttle with the given configuration.
+func NewThrottle(config ThrottleConfig) *Throttle {
+	return &Throttle{
+		config:  config,
+		entries: make(map[string]*throttleEntry),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether an attempt for key may proceed. When it may not, the
+// returned duration tells the caller how long to wait.
+func (t *Throttle) Allow(key string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := t.entry(key)
+	if entry == nil {
+		return 0, true
+	}
+
+	now := t.now()
+	until := entry.blockedUntil
+	if entry.lockedUntil.After(until) {
+		until = entry.lockedUntil
+	}
+
+	if now.Before(until) {
+		return until.Sub(now), false
+	}
+
+	return 0, true
+}
+
+// Failure records a failed attempt for key and returns the failure count and
+// the lockout expiry, which is zero when the key is not locked.
+func (t *Throttle) Failure(key string) (int, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	entry := t.entry(key)
+	if entry == nil {
+		if len(t.entries) >= throttlePruneSize {
+			t.prune(now)
+		}
+
+		entry = &throttleEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+
+	// Exponential backoff once the free attempts are used up
+	if extra := entry.failures - t.config.FreeAttempts; extra > 0 {
+		delay := t.config.BaseDelay << (extra - 1)
+		if delay <= 0 || delay > t.config.MaxDelay {
+			delay = t.config.MaxDelay
+		}
+		entry.blockedUntil = now.Add(delay)
+	}
+
+	if t.config.LockoutThreshold > 0 && entry.failures >= t.config.LockoutThreshold {
+		entry.lockedUntil = now.Add(t.config.LockoutDuration)
+	}
+
+	if entry.lockedUntil.After(now) {
+		return entry.failures, entry.lockedUntil
+	}
+
+	return entry.failures, time.Time{}
+}
+
+// Reset forgets all failures for key, for example after a successful login.
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.entries, key)
+}
+
+// Locked returns every key that is currently locked out.
+func (t *Throttle) Locked() []ThrottleState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+
+	var states []ThrottleState
+	for key, entry := range t.entries {
+		if entry.lockedUntil.After(now) {
+			states = append(states, ThrottleState{
+				Key:         key,
+				Failures:    entry.failures,
+				LastFailure: entry.lastFailure,
+				LockedUntil: entry.lockedUntil,
+			})
+		}
+	}
+
+	return states
+}
+
+// entry returns the entry for key, dropping it if it has gone stale.
+func (t *Throttle) entry(key string) *throttleEntry {
+	entry, exists := t.entries[key]
+	if !exists {
+		return nil
+	}
+
+	if t.stale(entry, t.now()) {
+		delete(t.entries, key)
+		return nil
+	}
+
+	return entry
+}
+
+func (t *Throttle) stale(entry *throttleEntry, now time.Time) bool {
+	return now.Sub(entry.lastFailure) > t.config.ResetAfter &&
+		now.After(entry.blockedUntil) &&
+		now.After(entry.lockedUntil)
+}
+
+func (t *Throttle) prune(now time.Time) {
+	for key, entry := range t.entries {
+		if t.stale(entry, now) {
+			delete(t.entries, key)
+		}
+	}
+}
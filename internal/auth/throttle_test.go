@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+var testThrottleConfig = ThrottleConfig{
+	FreeAttempts:     2,
+	BaseDelay:        time.Second,
+	MaxDelay:         4 * time.Second,
+	LockoutThreshold: 6,
+	LockoutDuration:  time.Minute,
+	ResetAfter:       time.Hour,
+}
+
+// newTestThrottle returns a throttle whose clock the test moves by hand.
+func newTestThrottle() (*Throttle, *time.Time) {
+	now := time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC)
+
+	throttle := NewThrottle(testThrottleConfig)
+	throttle.now = func() time.Time { return now }
+
+	return throttle, &now
+}
+
+func TestThrottleCountsFailures(t *testing.T) {
+	throttle, _ := newTestThrottle()
+
+	for want := 1; want <= 3; want++ {
+		if failures, _ := throttle.Failure("gate1"); failures != want {
+			t.Fatalf("failures = %d, want %d", failures, want)
+		}
+	}
+
+	// Keys are counted apart
+	if failures, _ := throttle.Failure("gate2"); failures != 1 {
+		t.Errorf("failures of another key = %d, want 1", failures)
+	}
+}
+
+func TestThrottleBacksOffAfterFreeAttempts(t *testing.T) {
+	throttle, now := newTestThrottle()
+
+	throttle.Failure("gate1")
+	throttle.Failure("gate1")
+	if _, ok := throttle.Allow("gate1"); !ok {
+		t.Fatal("free attempts were throttled")
+	}
+
+	// The delay doubles with every failure up to MaxDelay
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		throttle.Failure("gate1")
+
+		retryAfter, ok := throttle.Allow("gate1")
+		if ok || retryAfter != want {
+			t.Fatalf("Allow = %v, %v, want %v, false", retryAfter, ok, want)
+		}
+
+		*now = now.Add(want)
+		if _, ok := throttle.Allow("gate1"); !ok {
+			t.Fatalf("still throttled after %v", want)
+		}
+	}
+}
+
+func TestThrottleLocksOutAfterThreshold(t *testing.T) {
+	throttle, now := newTestThrottle()
+
+	for i := 1; i < testThrottleConfig.LockoutThreshold; i++ {
+		if _, lockedUntil := throttle.Failure("gate1"); !lockedUntil.IsZero() {
+			t.Fatalf("locked after %d failures", i)
+		}
+	}
+
+	_, lockedUntil := throttle.Failure("gate1")
+	if want := now.Add(testThrottleConfig.LockoutDuration); !lockedUntil.Equal(want) {
+		t.Fatalf("locked until %v, want %v", lockedUntil, want)
+	}
+
+	if retryAfter, ok := throttle.Allow("gate1"); ok || retryAfter != testThrottleConfig.LockoutDuration {
+		t.Errorf("Allow = %v, %v while locked", retryAfter, ok)
+	}
+
+	locked := throttle.Locked()
+	if len(locked) != 1 || locked[0].Key != "gate1" || locked[0].Failures != testThrottleConfig.LockoutThreshold {
+		t.Errorf("Locked = %+v", locked)
+	}
+}
+
+func TestThrottleLockoutExpires(t *testing.T) {
+	throttle, now := newTestThrottle()
+
+	for i := 0; i < testThrottleConfig.LockoutThreshold; i++ {
+		throttle.Failure("gate1")
+	}
+
+	*now = now.Add(testThrottleConfig.LockoutDuration)
+
+	if _, ok := throttle.Allow("gate1"); !ok {
+		t.Error("still locked after the lockout expired")
+	}
+	if locked := throttle.Locked(); len(locked) != 0 {
+		t.Errorf("Locked = %+v after the lockout expired", locked)
+	}
+
+	// Failures are forgotten after a quiet period
+	*now = now.Add(testThrottleConfig.ResetAfter + time.Second)
+
+	if failures, _ := throttle.Failure("gate1"); failures != 1 {
+		t.Errorf("failures = %d after the quiet period, want 1", failures)
+	}
+}
+
+func TestThrottleReset(t *testing.T) {
+	throttle, _ := newTestThrottle()
+
+	for i := 0; i < testThrottleConfig.LockoutThreshold; i++ {
+		throttle.Failure("gate1")
+	}
+
+	throttle.Reset("gate1")
+
+	if _, ok := throttle.Allow("gate1"); !ok {
+		t.Error("still locked after reset")
+	}
+	if failures, _ := throttle.Failure("gate1"); failures != 1 {
+		t.Errorf("failures = %d after reset, want 1", failures)
+	}
+}
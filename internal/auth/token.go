@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"attendance-app/internal/config"
+	"attendance-app/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+type contextKey int
+
+const operatorContextKey contextKey = iota
+
+// TokenIssuer signs and verifies operator JWTs.
+type TokenIssuer struct {
+	secret   []byte
+	lifetime time.Duration
+}
+
+// NewTokenIssuer creates a token issuer from the auth configuration.
+func NewTokenIssuer(config config.AuthConfig) *TokenIssuer {
+	return &TokenIssuer{
+		secret:   []byte(config.JWTSecret),
+		lifetime: config.TokenLifetime,
+	}
+}
+
+// Check reports whether the issuer can sign tokens that stay valid.
+func (t *TokenIssuer) Check() error {
+	if len(t.secret) == 0 {
+		return errors.New("JWT secret is not set")
+	}
+
+	if t.lifetime <= 0 {
+		return errors.New("token lifetime must be positive")
+	}
+
+	return nil
+}
+
+// GenerateToken signs a JWT for the operator.
+func (t *TokenIssuer) GenerateToken(operator *models.Operator) (string, error) {
+	now := time.Now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, models.Claims{
+		ID:           operator.ID,
+		Username:     operator.UserName,
+		TokenVersion: operator.TokenVersion,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(t.lifetime).Unix(),
+		},
+	})
+
+	return token.SignedString(t.secret)
+}
+
+// ParseToken verifies the signature and expiry of a JWT and returns its claims.
+func (t *TokenIssuer) ParseToken(tokenString string) (*models.Claims, error) {
+	var claims models.Claims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return t.secret, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// WithOperator stores the authenticated operator in the context.
+func WithOperator(ctx context.Context, operator *models.Operator) context.Context {
+	return context.WithValue(ctx, operatorContextKey, operator)
+}
+
+// OperatorFromContext returns the authenticated operator, if any.
+func OperatorFromContext(ctx context.Context) (*models.Operator, bool) {
+	operator, ok := ctx.Value(operatorContextKey).(*models.Operator)
+	return operator, ok && operator != nil
+}
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config holds every setting of the API server. It is loaded once at startup
+// and passed to the layers that need it.
+type Config struct {
+	Env      string
+	Server   ServerConfig
+	Database DatabaseConfig
+	Auth     AuthConfig
+	Tickets  TicketConfig
+	Sync     SyncConfig
+	Log      LogConfig
+}
+
+type ServerConfig struct {
+	Port            int
+	NgrokDeploy     bool
+	NgrokAuthToken  string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration // How long in-flight requests may take to finish on shutdown
+	MetricsToken    string        // Bearer token for /metrics, open when empty, required in production
+
+	// Proxies allowed to name the client in X-Forwarded-For. The ngrok agent
+	// runs on the same host and connects from loopback.
+	TrustedProxies []netip.Prefix
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+
+	QueryTimeout time.Duration // Limit of a single lookup or update
+	SyncTimeout  time.Duration // Limit of the queries of a sync batch
+
+	// Connection pool. Requests wait for a free connection up to their
+	// query timeout instead of opening more than MaxConns.
+	MaxConns          int
+	MinConns          int // Connections kept open for the burst when doors open
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// How queries are sent, see pgx.QueryExecMode. Use simple_protocol or
+	// exec behind PgBouncer in transaction mode, which cannot keep prepared
+	// statements.
+	QueryExecMode          string
+	StatementCacheCapacity int
+}
+
+type AuthConfig struct {
+	JWTSecret     string
+	TokenLifetime time.Duration
+}
+
+type TicketConfig struct {
+	SigningKey []byte // Ed25519 seed, nil when ticket signing is disabled
+	Validity   time.Duration
+}
+
+// SyncConfig limits the uploads of scanners, so a buggy one cannot exhaust
+// the memory or the database of the API.
+type SyncConfig struct {
+	MaxBodyBytes int64 // Largest upload body accepted
+	MaxBatchSize int   // Most tickets in one upload
+}
+
+type LogConfig struct {
+	Dir           string
+	RetentionDays int // Days of access log files to keep
+}
+
+// Environments the app can run in
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Defaults used when a setting is not provided
+var defaults = map[string]string{
+	"APP_ENV":                 EnvDevelopment,
+	"PORT":                    "8080",
+	"NGROK_DEPLOY":            "false",
+	"SERVER_READ_TIMEOUT":     "30s",
+	"SERVER_WRITE_TIMEOUT":    "60s",
+	"SERVER_IDLE_TIMEOUT":     "120s",
+	"SERVER_SHUTDOWN_TIMEOUT": "30s",
+	"TRUSTED_PROXIES":         "127.0.0.1/32,::1/128",
+	"DB_PORT":                 "5432",
+	"DB_SSLMODE":              "disable",
+	"DB_QUERY_TIMEOUT":        "5s",
+	"DB_SYNC_TIMEOUT":         "30s",
+	"DB_MAX_CONNS":            "20",
+	"DB_MIN_CONNS":            "2",
+	"DB_MAX_CONN_LIFETIME":    "1h",
+	"DB_MAX_CONN_IDLE_TIME":   "15m",
+	"DB_HEALTH_CHECK_PERIOD":  "30s",
+	"DB_QUERY_EXEC_MODE":      "cache_statement",
+	"DB_STATEMENT_CACHE_SIZE": "512",
+	"JWT_TTL":                 "24h",
+	"TICKET_VALIDITY":         "720h",
+	"SYNC_MAX_BODY_BYTES":     "10485760",
+	"SYNC_MAX_BATCH_SIZE":     "50000",
+	"LOG_DIR":                 "logs",
+	"LOG_RETENTION_DAYS":      "7",
+}
+
+// Load reads the configuration from, in increasing priority, the defaults,
+// an optional .env style file, environment variables and command line flags.
+// All problems are reported together so the server fails fast with one message.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("attendance-app", flag.ContinueOnError)
+
+	configFile := flags.String("config", "", "path to a .env style configuration file (default .env when present)")
+	port := flags.Int("port", 0, "port to listen on")
+	ngrokDeploy := flags.Bool("ngrok", false, "serve through an ngrok tunnel")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	values, err := readFile(*configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	applyEnv(values)
+
+	// Flags override everything
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			values["PORT"] = strconv.Itoa(*port)
+		case "ngrok":
+			values["NGROK_DEPLOY"] = strconv.FormatBool(*ngrokDeploy)
+		}
+	})
+
+	return parse(values)
+}
+
+// LoadDatabase reads only the database settings, from the file and the
+// environment, for tools such as the migrate command that do not serve HTTP.
+func LoadDatabase(configFile string) (DatabaseConfig, error) {
+	values, err := readFile(configFile)
+	if err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	applyEnv(values)
+
+	p := parser{values: values}
+	cfg := p.database()
+
+	if err := errors.Join(p.errs...); err != nil {
+		return DatabaseConfig{}, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv lets environment variables override the file.
+func applyEnv(values map[string]string) {
+	for key := range knownKeys(values) {
+		if value, ok := os.LookupEnv(key); ok {
+			values[key] = value
+		}
+	}
+}
+
+// readFile loads the configuration file. The default .env is optional, a file
+// named explicitly must exist.
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	explicit := path != ""
+	if !explicit {
+		path = ".env"
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// knownKeys returns every key the config understands plus those in the file.
+func knownKeys(values map[string]string) map[string]struct{} {
+	keys := map[string]struct{}{}
+
+	for _, key := range []string{
+		"APP_ENV", "PORT", "NGROK_DEPLOY", "NGROK_AUTHTOKEN",
+		"SERVER_READ_TIMEOUT", "SERVER_WRITE_TIMEOUT", "SERVER_IDLE_TIMEOUT", "SERVER_SHUTDOWN_TIMEOUT",
+		"METRICS_TOKEN", "TRUSTED_PROXIES",
+		"DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE",
+		"DB_QUERY_TIMEOUT", "DB_SYNC_TIMEOUT",
+		"DB_MAX_CONNS", "DB_MIN_CONNS", "DB_MAX_CONN_LIFETIME", "DB_MAX_CONN_IDLE_TIME", "DB_HEALTH_CHECK_PERIOD",
+		"DB_QUERY_EXEC_MODE", "DB_STATEMENT_CACHE_SIZE",
+		"JWT_SECRET_KEY", "JWT_TTL",
+		"TICKET_SIGNING_KEY", "TICKET_VALIDITY",
+		"SYNC_MAX_BODY_BYTES", "SYNC_MAX_BATCH_SIZE",
+		"LOG_DIR", "LOG_RETENTION_DAYS",
+	} {
+		keys[key] = struct{}{}
+	}
+
+	for key := range values {
+		keys[key] = struct{}{}
+	}
+
+	return keys
+}
+
+func parse(values map[string]string) (*Config, error) {
+	p := parser{values: values}
+
+	cfg := &Config{
+		Env: p.oneOf("APP_ENV", EnvDevelopment, EnvProduction),
+		Server: ServerConfig{
+			Port:            p.port("PORT"),
+			NgrokDeploy:     p.bool("NGROK_DEPLOY"),
+			NgrokAuthToken:  p.optional("NGROK_AUTHTOKEN"),
+			ReadTimeout:     p.duration("SERVER_READ_TIMEOUT"),
+			WriteTimeout:    p.duration("SERVER_WRITE_TIMEOUT"),
+			IdleTimeout:     p.duration("SERVER_IDLE_TIMEOUT"),
+			ShutdownTimeout: p.duration("SERVER_SHUTDOWN_TIMEOUT"),
+			MetricsToken:    p.optional("METRICS_TOKEN"),
+			TrustedProxies:  p.prefixes("TRUSTED_PROXIES"),
+		},
+		Database: p.database(),
+		Auth: AuthConfig{
+			JWTSecret:     p.required("JWT_SECRET_KEY"),
+			TokenLifetime: p.duration("JWT_TTL"),
+		},
+		Tickets: TicketConfig{
+			SigningKey: p.hexKey("TICKET_SIGNING_KEY", 32),
+			Validity:   p.duration("TICKET_VALIDITY"),
+		},
+		Sync: SyncConfig{
+			MaxBodyBytes: int64(p.positiveInt("SYNC_MAX_BODY_BYTES")),
+			MaxBatchSize: p.positiveInt("SYNC_MAX_BATCH_SIZE"),
+		},
+		Log: LogConfig{
+			Dir:           p.required("LOG_DIR"),
+			RetentionDays: p.positiveInt("LOG_RETENTION_DAYS"),
+		},
+	}
+
+	if cfg.Auth.JWTSecret != "" && len(cfg.Auth.JWTSecret) < 32 {
+		p.fail("JWT_SECRET_KEY must be at least 32 characters")
+	}
+
+	// Metrics name routes and count failed logins, keep them private in production
+	if cfg.Env == EnvProduction && cfg.Server.MetricsToken == "" {
+		p.fail("METRICS_TOKEN is required when APP_ENV is production")
+	}
+
+	if cfg.Server.NgrokDeploy && cfg.Server.NgrokAuthToken == "" {
+		p.fail("NGROK_AUTHTOKEN is required when NGROK_DEPLOY is true")
+	}
+
+	if err := errors.Join(p.errs...); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (p *parser) database() DatabaseConfig {
+	cfg := DatabaseConfig{
+		Host:     p.required("DB_HOST"),
+		Port:     p.port("DB_PORT"),
+		User:     p.required("DB_USER"),
+		Password: p.optional("DB_PASSWORD"),
+		Name:     p.required("DB_NAME"),
+		SSLMode:  p.oneOf("DB_SSLMODE", "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+
+		QueryTimeout: p.duration("DB_QUERY_TIMEOUT"),
+		SyncTimeout:  p.duration("DB_SYNC_TIMEOUT"),
+
+		MaxConns:          p.positiveInt("DB_MAX_CONNS"),
+		MinConns:          p.nonNegativeInt("DB_MIN_CONNS"),
+		MaxConnLifetime:   p.duration("DB_MAX_CONN_LIFETIME"),
+		MaxConnIdleTime:   p.duration("DB_MAX_CONN_IDLE_TIME"),
+		HealthCheckPeriod: p.duration("DB_HEALTH_CHECK_PERIOD"),
+
+		QueryExecMode:          p.oneOf("DB_QUERY_EXEC_MODE", "cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol"),
+		StatementCacheCapacity: p.positiveInt("DB_STATEMENT_CACHE_SIZE"),
+	}
+
+	if cfg.MinConns > cfg.MaxConns {
+		p.fail("DB_MIN_CONNS (%d) cannot exceed DB_MAX_CONNS (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+
+	return cfg
+}
+
+// URL returns the database as a postgres:// URL.
+func (c DatabaseConfig) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     c.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(c.SSLMode),
+	}
+
+	return u.String()
+}
+
+// parser converts raw values and collects every error it meets.
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	p.errs = append(p.errs, fmt.Errorf(format, args...))
+}
+
+func (p *parser) optional(key string) string {
+	if value, ok := p.values[key]; ok && value != "" {
+		return value
+	}
+
+	return defaults[key]
+}
+
+func (p *parser) required(key string) string {
+	value := p.optional(key)
+	if value == "" {
+		p.fail("%s is required", key)
+	}
+
+	return value
+}
+
+func (p *parser) port(key string) int {
+	value := p.optional(key)
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		p.fail("%s must be a number between 1 and 65535, got %q", key, value)
+		return 0
+	}
+
+	return port
+}
+
+func (p *parser) positiveInt(key string) int {
+	value := p.optional(key)
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		p.fail("%s must be a positive number, got %q", key, value)
+	}
+
+	return n
+}
+
+func (p *parser) nonNegativeInt(key string) int {
+	value := p.optional(key)
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		p.fail("%s must be zero or a positive number, got %q", key, value)
+	}
+
+	return n
+}
+
+func (p *parser) bool(key string) bool {
+	value := p.optional(key)
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.fail("%s must be true or false, got %q", key, value)
+	}
+
+	return b
+}
+
+func (p *parser) duration(key string) time.Duration {
+	value := p.optional(key)
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		p.fail("%s must be a positive duration such as 30s or 24h, got %q", key, value)
+	}
+
+	return d
+}
+
+func (p *parser) oneOf(key string, allowed ...string) string {
+	value := p.optional(key)
+
+	for _, a := range allowed {
+		if value == a {
+			return value
+		}
+	}
+
+	p.fail("%s must be one of %v, got %q", key, allowed, value)
+
+	return value
+}
+
+// hexKey parses an optional hex encoded key of the given length in bytes.
+func (p *parser) hexKey(key string, size int) []byte {
+	value := p.optional(key)
+	if value == "" {
+		return nil
+	}
+
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != size {
+		p.fail("%s must be %d hex characters", key, size*2)
+		return nil
+	}
+
+	return decoded
+}
+
+// prefixes parses a comma separated list of addresses and CIDR ranges. A
+// single address stands for itself.
+func (p *parser) prefixes(key string) []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for _, part := range strings.Split(p.optional(key), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(part); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			p.fail("%s must list addresses or CIDR ranges, got %q", key, part)
+			continue
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// required holds the settings that have no default
+var required = map[string]string{
+	"DB_HOST":        "localhost",
+	"DB_USER":        "attendance",
+	"DB_NAME":        "attendance",
+	"JWT_SECRET_KEY": "0123456789abcdef0123456789abcdef",
+}
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := parse(copyValues(required))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Log.Dir != "logs" || cfg.Log.RetentionDays != 7 {
+		t.Errorf("log = %+v, want logs kept 7 days", cfg.Log)
+	}
+
+	if len(cfg.Server.TrustedProxies) != 2 || !cfg.Server.TrustedProxies[0].Contains(netip.MustParseAddr("127.0.0.1")) {
+		t.Errorf("trusted proxies = %v, want loopback", cfg.Server.TrustedProxies)
+	}
+}
+
+func TestParseRejectsInvalidValues(t *testing.T) {
+	tests := map[string]string{
+		"LOG_RETENTION_DAYS": "0",
+		"TRUSTED_PROXIES":    "10.0.0.0/8, proxy.local",
+		"DB_PORT":            "70000",
+		"JWT_SECRET_KEY":     "short",
+	}
+
+	for key, value := range tests {
+		values := copyValues(required)
+		values[key] = value
+
+		if _, err := parse(values); err == nil {
+			t.Errorf("%s=%q was accepted", key, value)
+		}
+	}
+}
+
+func TestParseRequiresMetricsTokenInProduction(t *testing.T) {
+	values := copyValues(required)
+	values["APP_ENV"] = EnvProduction
+
+	if _, err := parse(values); err == nil {
+		t.Fatal("production without METRICS_TOKEN was accepted")
+	}
+
+	values["METRICS_TOKEN"] = "scrape-token"
+
+	if _, err := parse(values); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	values := copyValues(required)
+	values["TRUSTED_PROXIES"] = "10.0.0.0/8, 192.168.1.7"
+
+	cfg, err := parse(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32")}
+	if len(cfg.Server.TrustedProxies) != len(want) {
+		t.Fatalf("trusted proxies = %v, want %v", cfg.Server.TrustedProxies, want)
+	}
+	for i := range want {
+		if cfg.Server.TrustedProxies[i] != want[i] {
+			t.Errorf("trusted proxies = %v, want %v", cfg.Server.TrustedProxies, want)
+		}
+	}
+}
+
+func copyValues(values map[string]string) map[string]string {
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+func TestLoadFileWithoutOptionalSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+
+	file := "DB_HOST=localhost\nDB_USER=attendance\nDB_NAME=attendance\nJWT_SECRET_KEY=0123456789abcdef0123456789abcdef\n"
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Log.Dir == "" {
+		t.Error("LOG_DIR has no default")
+	}
+}
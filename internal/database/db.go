@@ -1,41 +1,69 @@
 package db
 
 import (
-	"database/sql"
+	"attendance-app/internal/config"
+	"context"
 	"fmt"
 	"log"
-	"os"
 
-	_ "github.com/lib/pq" // Postgres driver
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	DB *sql.DB
+	Pool *pgxpool.Pool
 )
 
-// InitializeDB sets up the database connection.
-func InitializeDB() {
-	connStr := fmt.Sprintf("host=%s user=%s password=%s dbname=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_SSLMODE"),
-	)
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// InitializeDB opens the connection pool and checks that the database answers.
+func InitializeDB(ctx context.Context, config config.DatabaseConfig) error {
+	poolConfig, err := pgxpool.ParseConfig(config.URL())
+	if err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	poolConfig.MaxConns = int32(config.MaxConns)
+	poolConfig.MinConns = int32(config.MinConns)
+	poolConfig.MaxConnLifetime = config.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
 
-	var err error
-	DB, err = sql.Open("postgres", connStr)
+	poolConfig.ConnConfig.DefaultQueryExecMode = queryExecModes[config.QueryExecMode]
+	poolConfig.ConnConfig.StatementCacheCapacity = config.StatementCacheCapacity
+	poolConfig.ConnConfig.DescriptionCacheCapacity = config.StatementCacheCapacity
 
+	Pool, err = pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	// Test the connection
-	err = DB.Ping()
+	err = Pool.Ping(ctx)
 
 	if err != nil {
-		log.Fatal("Failed to ping database:", err)
+		Pool.Close()
+		return fmt.Errorf("failed to ping database %s:%d: %w", config.Host, config.Port, err)
 	}
 
-	log.Println("Connected to database")
+	log.Printf("Connected to database, pool of up to %d connections", config.MaxConns)
+
+	return nil
+}
+
+// Close closes the connection pool once in-flight queries have finished.
+func Close() {
+	if Pool == nil {
+		return
+	}
+
+	Pool.Close()
+
+	log.Println("Database connection closed")
 }
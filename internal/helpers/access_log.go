@@ -0,0 +1,117 @@
+package helpers
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/redact"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// accessLog receives one JSON line per response, nil until InitAccessLog runs.
+var accessLog io.Writer
+
+type accessLogEntry struct {
+	Time        string            `json:"time"`
+	ResponseID  string            `json:"response_id"`
+	Method      string            `json:"method"`
+	Route       string            `json:"route"`
+	Path        string            `json:"path"`
+	OperatorID  *int              `json:"operator_id,omitempty"`
+	Status      int               `json:"status"`
+	LatencyMs   float64           `json:"latency_ms"`
+	ClientIP    string            `json:"client_ip"`
+	UserAgent   string            `json:"user_agent"`
+	Headers     map[string]string `json:"request_headers,omitempty"`
+	RequestBody interface{}       `json:"request_body,omitempty"`
+	Message     string            `json:"message"`
+	Errors      interface{}       `json:"errors,omitempty"`
+}
+
+// InitAccessLog starts writing access logs to daily files in dir, keeping
+// retentionDays days of files. The returned closer closes the current file.
+func InitAccessLog(dir string, retentionDays int) (io.Closer, error) {
+	file, err := NewRotatingFile(dir, "responses", retentionDays)
+	if err != nil {
+		return nil, err
+	}
+
+	accessLog = file
+
+	return file, nil
+}
+
+func writeAccessLog(r *http.Request, responseID, message string, errors interface{}, httpCode int) {
+	if accessLog == nil {
+		return
+	}
+
+	now := time.Now()
+
+	entry := accessLogEntry{
+		Time:       now.Format(time.RFC3339Nano),
+		ResponseID: responseID,
+		Method:     r.Method,
+		Route:      routeTemplate(r),
+		Path:       r.URL.Path,
+		Status:     httpCode,
+		ClientIP:   ClientIP(r),
+		UserAgent:  r.UserAgent(),
+		Headers:    redact.Headers(r.Header),
+		Message:    message,
+		Errors:     errors,
+	}
+
+	if operator, ok := auth.OperatorFromContext(r.Context()); ok {
+		entry.OperatorID = &operator.ID
+	}
+
+	if info := requestInfoFrom(r); info != nil {
+		entry.LatencyMs = float64(now.Sub(info.start).Microseconds()) / 1000
+		entry.RequestBody = loggedBody(info.body)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Println("Error encoding access log:", err)
+		return
+	}
+
+	if _, err := accessLog.Write(append(line, '\n')); err != nil {
+		log.Println("Error writing access log:", err)
+	}
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return r.URL.Path
+}
+
+// loggedBody returns the request body with sensitive fields redacted. Bodies
+// that cannot be parsed are left out, as they could hide a password.
+func loggedBody(body *cappedBuffer) interface{} {
+	if body.Len() == 0 {
+		return nil
+	}
+
+	if body.truncated {
+		return fmt.Sprintf("[%d+ bytes, not logged]", body.Len())
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body.Bytes(), &value); err != nil {
+		return fmt.Sprintf("[%d bytes, not JSON, not logged]", body.Len())
+	}
+
+	return redact.JSON(value)
+}
@@ -0,0 +1,138 @@
+package helpers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// Media types the API reads and writes. MessagePack carries the same fields
+// as JSON, under the same names, in a smaller and cheaper to parse form.
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgPack = "application/msgpack"
+)
+
+// Names clients use for MessagePack, none of them registered
+var msgpackTypes = map[string]bool{
+	"application/msgpack":     true,
+	"application/x-msgpack":   true,
+	"application/vnd.msgpack": true,
+}
+
+// The MessagePack timestamp extension has no room for an offset, so times are
+// written as RFC 3339 strings like JSON and keep the event's time zone.
+// Timestamps are still read from older clients.
+func init() {
+	msgpack.Register(time.Time{}, encodeTime, decodeTime)
+}
+
+func encodeTime(e *msgpack.Encoder, v reflect.Value) error {
+	return e.EncodeString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+}
+
+func decodeTime(d *msgpack.Decoder, v reflect.Value) error {
+	code, err := d.PeekCode()
+	if err != nil {
+		return err
+	}
+
+	var t time.Time
+	if msgpcode.IsString(code) {
+		value, err := d.DecodeString()
+		if err != nil {
+			return err
+		}
+
+		if t, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return err
+		}
+	} else if t, err = d.DecodeTime(); err != nil {
+		return err
+	}
+
+	v.Set(reflect.ValueOf(t))
+
+	return nil
+}
+
+// ResponseContentType returns the media type the client prefers in Accept,
+// JSON unless it ranks MessagePack higher.
+func ResponseContentType(r *http.Request) string {
+	jsonQ, msgpackQ := -1.0, -1.0
+	wildcard := -1.0
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		switch {
+		case msgpackTypes[mediaType]:
+			msgpackQ = max(msgpackQ, q)
+		case mediaType == ContentTypeJSON:
+			jsonQ = q
+		case mediaType == "*/*" || mediaType == "application/*":
+			wildcard = max(wildcard, q)
+		}
+	}
+
+	// Types not named are as acceptable as the wildcard
+	if jsonQ < 0 {
+		jsonQ = wildcard
+	}
+
+	if msgpackQ > 0 && msgpackQ > jsonQ {
+		return ContentTypeMsgPack
+	}
+
+	return ContentTypeJSON
+}
+
+// DecodeBody decodes the request body as MessagePack or JSON depending on its
+// Content-Type, JSON when none is given, and refuses unknown fields.
+func DecodeBody(r *http.Request, v interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if msgpackTypes[mediaType] {
+		decoder := msgpack.NewDecoder(r.Body)
+		decoder.SetCustomStructTag("json")
+		decoder.DisallowUnknownFields(true)
+
+		return decoder.Decode(v)
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+// encode writes v in the given media type.
+func encode(w io.Writer, contentType string, v interface{}) error {
+	if contentType == ContentTypeMsgPack {
+		encoder := msgpack.NewEncoder(w)
+		encoder.SetCustomStructTag("json")
+		encoder.UseCompactInts(true)
+
+		return encoder.Encode(v)
+	}
+
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestResponseContentType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ContentTypeJSON},
+		{"*/*", ContentTypeJSON},
+		{"application/json", ContentTypeJSON},
+		{"application/msgpack", ContentTypeMsgPack},
+		{"application/x-msgpack", ContentTypeMsgPack},
+		{"application/vnd.msgpack, application/json;q=0.9", ContentTypeMsgPack},
+		{"application/msgpack;q=0.5, application/json", ContentTypeJSON},
+		{"application/msgpack;q=0.5, */*;q=0.1", ContentTypeMsgPack},
+		{"application/msgpack, */*", ContentTypeJSON},
+		{"application/msgpack;q=0", ContentTypeJSON},
+		{"text/html", ContentTypeJSON},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", test.accept)
+
+		if got := ResponseContentType(r); got != test.want {
+			t.Errorf("ResponseContentType(Accept: %q) = %q, want %q", test.accept, got, test.want)
+		}
+	}
+}
+
+// Times are always fields of a response, which is where the codec applies
+type timeField struct {
+	Time time.Time
+}
+
+func TestMessagePackTimeKeepsOffset(t *testing.T) {
+	want := time.Date(2025, 1, 10, 19, 0, 0, 0, time.FixedZone("WIT", 9*60*60))
+
+	var body bytes.Buffer
+	if err := encode(&body, ContentTypeMsgPack, timeField{want}); err != nil {
+		t.Fatal(err)
+	}
+
+	var text map[string]string
+	if err := msgpack.Unmarshal(body.Bytes(), &text); err != nil {
+		t.Fatal(err)
+	}
+
+	if text["Time"] != "2025-01-10T19:00:00+09:00" {
+		t.Errorf("encoded as %q, want 2025-01-10T19:00:00+09:00", text["Time"])
+	}
+
+	var field timeField
+	if err := msgpack.Unmarshal(body.Bytes(), &field); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := field.Time; !got.Equal(want) || got.Format(time.RFC3339) != "2025-01-10T19:00:00+09:00" {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
+
+func TestMessagePackTimeReadsTimestamps(t *testing.T) {
+	want := time.Date(2025, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	// The timestamp extension older clients send
+	var body bytes.Buffer
+	encoder := msgpack.NewEncoder(&body)
+	if err := encoder.EncodeMapLen(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.EncodeString("Time"); err != nil {
+		t.Fatal(err)
+	}
+	if err := encoder.EncodeTime(want); err != nil {
+		t.Fatal(err)
+	}
+
+	var field timeField
+	if err := msgpack.Unmarshal(body.Bytes(), &field); err != nil {
+		t.Fatal(err)
+	}
+
+	if !field.Time.Equal(want) {
+		t.Errorf("decoded %v, want %v", field.Time, want)
+	}
+}
@@ -0,0 +1,132 @@
+package helpers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log files are named <prefix>-YYMMDD.log
+const logDateFormat = "060102"
+
+// RotatingFile writes to a log file per day. The date is checked on every
+// write so a long running process moves to a new file at midnight, and files
+// older than the retention period are removed when it does.
+type RotatingFile struct {
+	mu            sync.Mutex
+	dir           string
+	prefix        string
+	retentionDays int
+	date          string
+	file          *os.File
+	now           func() time.Time
+}
+
+// NewRotatingFile creates the log folder and opens today's file.
+func NewRotatingFile(dir, prefix string, retentionDays int) (*RotatingFile, error) {
+	return newRotatingFile(dir, prefix, retentionDays, time.Now)
+}
+
+func newRotatingFile(dir, prefix string, retentionDays int, now func() time.Time) (*RotatingFile, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create log folder: %w", err)
+	}
+
+	f := &RotatingFile{
+		dir:           dir,
+		prefix:        prefix,
+		retentionDays: retentionDays,
+		now:           now,
+	}
+
+	if err := f.rotate(f.now()); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write appends p to the current day's file.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	if now.Format(logDateFormat) != f.date {
+		if err := f.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	return f.file.Write(p)
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+func (f *RotatingFile) rotate(now time.Time) error {
+	date := now.Format(logDateFormat)
+	name := filepath.Join(f.dir, f.prefix+"-"+date+".log")
+
+	file, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	if f.file != nil {
+		f.file.Close()
+	}
+
+	f.file = file
+	f.date = date
+
+	f.removeExpired(now)
+
+	return nil
+}
+
+// removeExpired deletes log files older than the retention period.
+func (f *RotatingFile) removeExpired(now time.Time) {
+	if f.retentionDays <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(f.dir, f.prefix+"-*.log"))
+	if err != nil {
+		return
+	}
+
+	today, _ := time.ParseInLocation(logDateFormat, now.Format(logDateFormat), now.Location())
+	cutoff := today.AddDate(0, 0, -f.retentionDays)
+
+	for _, match := range matches {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), f.prefix+"-"), ".log")
+
+		fileDate, err := time.ParseInLocation(logDateFormat, date, now.Location())
+		if err != nil {
+			continue
+		}
+
+		if fileDate.Before(cutoff) {
+			if err := os.Remove(match); err != nil {
+				log.Println("Failed to remove expired log file:", err)
+			}
+		}
+	}
+}
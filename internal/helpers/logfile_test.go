@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func logFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestRotatingFileRotatesDaily(t *testing.T) {
+	dir := t.TempDir()
+
+	now := time.Date(2025, 1, 10, 23, 59, 0, 0, time.Local)
+
+	f, err := newRotatingFile(dir, "responses", 7, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte("first\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if _, err := f.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"responses-250110.log": "first\n", "responses-250111.log": "second\n"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRotatingFileRemovesExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"responses-250101.log", // Expired when the file is opened
+		"responses-250106.log", // Expired when the day changes
+		"responses-250107.log",
+		"responses-250109.log",
+		"responses-latest.log", // Not a dated log file
+		"other-250101.log",     // Another prefix
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2025, 1, 9, 8, 0, 0, 0, time.Local)
+
+	f, err := newRotatingFile(dir, "responses", 3, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if got := logFiles(t, dir); len(got) != 5 || got[1] != "responses-250106.log" {
+		t.Fatalf("files = %v, want those of the last 3 days kept", got)
+	}
+
+	now = now.AddDate(0, 0, 1)
+
+	if _, err := f.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"other-250101.log", "responses-250107.log", "responses-250109.log", "responses-250110.log", "responses-latest.log"}
+
+	got := logFiles(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Proxies whose X-Forwarded-For entries are believed, none until
+// TrustProxies is called
+var trustedProxies []netip.Prefix
+
+// TrustProxies sets the addresses of the proxies in front of the app, such as
+// the ngrok agent, which append the address they received a request from to
+// X-Forwarded-For. It is meant to be called once at startup.
+func TrustProxies(prefixes []netip.Prefix) {
+	trustedProxies = prefixes
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the address of the client that made the request. Only a
+// trusted proxy may name another address in X-Forwarded-For, and as a client
+// can send the header with anything in it, the right-most entry not added by
+// a trusted proxy is taken.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(forwarded[i])
+		if entry == "" {
+			continue
+		}
+
+		ip = entry
+		if !isTrustedProxy(entry) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// Only the start of a request body is kept for the access log
+const maxLoggedBodySize = 4096
+
+type requestInfoKey struct{}
+
+// requestInfo is what the access log needs to know about a request.
+type requestInfo struct {
+	start time.Time
+	body  *cappedBuffer
+}
+
+// TrackRequest records the start time of the request and keeps a copy of the
+// first bytes of its body as the handler reads it.
+func TrackRequest(r *http.Request) *http.Request {
+	info := &requestInfo{
+		start: time.Now(),
+		body:  &cappedBuffer{limit: maxLoggedBodySize},
+	}
+
+	tracked := r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		tracked.Body = teeReadCloser{
+			Reader: io.TeeReader(r.Body, info.body),
+			Closer: r.Body,
+		}
+	}
+
+	return tracked
+}
+
+func requestInfoFrom(r *http.Request) *requestInfo {
+	info, _ := r.Context().Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// cappedBuffer keeps the first limit bytes written to it.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	TrustProxies([]netip.Prefix{netip.MustParsePrefix("127.0.0.1/32"), netip.MustParsePrefix("10.0.0.0/8")})
+	defer TrustProxies(nil)
+
+	tests := []struct {
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"203.0.113.7:5000", nil, "203.0.113.7"},
+		// Only a trusted proxy may name the client
+		{"203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"127.0.0.1:5000", nil, "127.0.0.1"},
+		{"127.0.0.1:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		// A client cannot hide behind entries it adds itself
+		{"127.0.0.1:5000", []string{"192.0.2.99, 198.51.100.1"}, "198.51.100.1"},
+		{"127.0.0.1:5000", []string{"192.0.2.99", "198.51.100.1"}, "198.51.100.1"},
+		// Trusted proxies in the chain are skipped
+		{"127.0.0.1:5000", []string{"192.0.2.99, 198.51.100.1, 10.1.2.3"}, "198.51.100.1"},
+		{"127.0.0.1:5000", []string{"10.1.2.3"}, "10.1.2.3"},
+		{"[::ffff:127.0.0.1]:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, value := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+
+		if got := ClientIP(r); got != test.want {
+			t.Errorf("ClientIP(%s, %q) = %q, want %q", test.remoteAddr, test.forwarded, got, test.want)
+		}
+	}
+}
@@ -1,11 +1,9 @@
 package helpers
 
 import (
-	"encoding/json"
-	"log"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/logger"
 	"net/http"
-	"os"
-	"time"
 
 	"unicode"
 
@@ -19,49 +17,28 @@ type Response struct {
 	Message    string      `json:"message"`
 	Data       interface{} `json:"data,omitempty"`
 	Errors     interface{} `json:"errors,omitempty"`
-}
-
-// Set up a logger
-var responseLogger *log.Logger
-
-func init() {
-	// Ensure the log folder exists
-	logFolder := "logs"
-	if err := os.MkdirAll(logFolder, os.ModePerm); err != nil {
-		log.Fatalf("Failed to create log folder: %v", err)
-	}
-
-	// Generate the file name with the current date in -YYMMDD format
-	currentDate := time.Now().Format("060102") // YYMMDD format
-	logFileName := logFolder + "/responses-" + currentDate + ".log"
-
-	// Create or open the log file
-	logFile, err := os.OpenFile(logFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
-	}
-
-	// Initialize the logger
-	responseLogger = log.New(logFile, "", log.LstdFlags)
+	Meta       interface{} `json:"meta,omitempty"`
 }
 
 // SetResponse formats and sends JSON responses while logging request and response
 func SetResponse(w http.ResponseWriter, r *http.Request, message string, data interface{}, httpCode int) {
-	body := ""
+	SetResponseWithMeta(w, r, message, data, nil, httpCode)
+}
 
-	// Log the request
-	requestLog := map[string]interface{}{
-		"body":    string(body),
-		"headers": r.Header,
+// SetResponseWithMeta is SetResponse with extra information next to the data,
+// such as keys the client needs to interpret it.
+func SetResponseWithMeta(w http.ResponseWriter, r *http.Request, message string, data interface{}, meta interface{}, httpCode int) {
+	// The response ID is the request ID so it matches the logs of the request
+	responseID := logger.RequestID(r.Context())
+	if responseID == "" {
+		responseID = generateUUID()
 	}
 
-	// Generate a unique response ID
-	responseID := generateUUID()
-
 	// Create the response struct
 	response := Response{
 		ResponseID: responseID,
 		Message:    message,
+		Meta:       meta,
 	}
 
 	// Check HTTP status code and set data/errors
@@ -70,12 +47,8 @@ func SetResponse(w http.ResponseWriter, r *http.Request, message string, data in
 			response.Data = data
 		}
 	} else {
-		if httpCode == 422 {
-			validationResponse := FormatValidationErrors(data)
-
-			if data != nil {
-				response.Errors = validationResponse
-			}
+		if validationErrors, ok := data.(validator.ValidationErrors); ok && httpCode == 422 {
+			response.Errors = FormatValidationErrors(validationErrors)
 		} else {
 			if data != nil {
 				response.Errors = data
@@ -83,26 +56,31 @@ func SetResponse(w http.ResponseWriter, r *http.Request, message string, data in
 		}
 	}
 
-	// Combine request and response for logging
-	logEntry := map[string]interface{}{
-		"request": requestLog,
-		"response": map[string]interface{}{
-			"response_id": responseID,
-			"message":     message,
-		},
-		"http_code": httpCode,
-	}
+	// Log the request and response
+	writeAccessLog(r, responseID, message, response.Errors, httpCode)
 
-	if data != nil {
-		logEntry["response"].(map[string]interface{})["data"] = data
-	}
+	// Set response headers and write the response in the encoding the client asked for
+	contentType := ResponseContentType(r)
 
-	// Set response headers and write the JSON response
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(httpCode)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Println("Error encoding response:", err)
+	if err := encode(w, contentType, response); err != nil {
+		logger.Println(r.Context(), "Error encoding response:", err)
+	}
+}
+
+// SetNotModified answers a conditional request whose copy is still current.
+// Like every 304 it has no body, only the headers already set on w.
+func SetNotModified(w http.ResponseWriter, r *http.Request) {
+	responseID := logger.RequestID(r.Context())
+	if responseID == "" {
+		responseID = generateUUID()
 	}
+
+	writeAccessLog(r, responseID, "Not modified", nil, http.StatusNotModified)
+
+	w.WriteHeader(http.StatusNotModified)
 }
 
 func generateUUID() string {
@@ -120,6 +98,16 @@ func FormatValidationErrors(err interface{}) map[string][]string {
 			message = fieldName + " is required."
 		case "max":
 			message = fieldName + " cannot exceed " + fieldErr.Param() + " characters."
+		case "min":
+			message = fieldName + " must be at least " + fieldErr.Param() + " characters."
+		case "eqfield":
+			message = fieldName + " does not match " + ToSnakeCase(fieldErr.Param()) + "."
+		case "nefield":
+			message = fieldName + " must be different from " + ToSnakeCase(fieldErr.Param()) + "."
+		case "password":
+			if err := auth.ValidatePassword(fieldErr.Value().(string)); err != nil {
+				message = fieldName + " " + err.Error() + "."
+			}
 		}
 		validationErrors[fieldName] = append(validationErrors[fieldName], message)
 	}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID stores the request ID in the context.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the request the context belongs to, if any.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Printf logs like log.Printf, prefixed with the request ID from the context.
+func Printf(ctx context.Context, format string, args ...interface{}) {
+	log.Print(prefix(ctx) + fmt.Sprintf(format, args...))
+}
+
+// Println logs like log.Println, prefixed with the request ID from the context.
+func Println(ctx context.Context, args ...interface{}) {
+	log.Print(prefix(ctx) + fmt.Sprintln(args...))
+}
+
+func prefix(ctx context.Context) string {
+	if requestID := RequestID(ctx); requestID != "" {
+		return "request_id=" + requestID + " "
+	}
+
+	return ""
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the statistics of the pgx pool on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	acquireDuration   *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	newConns          *prometheus.Desc
+	lifetimeDestroys  *prometheus.Desc
+	idleDestroys      *prometheus.Desc
+}
+
+// RegisterPoolStats exports the statistics of the database connection pool.
+// A rising attendance_db_pool_empty_acquires_total means requests are waiting
+// for a connection and DB_MAX_CONNS may be too low.
+func RegisterPoolStats(pool *pgxpool.Pool) {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	registry.MustRegister(&poolCollector{
+		pool:              pool,
+		acquiredConns:     desc("acquired_conns", "Connections currently in use."),
+		idleConns:         desc("idle_conns", "Connections currently idle."),
+		constructingConns: desc("constructing_conns", "Connections being opened."),
+		totalConns:        desc("total_conns", "Open connections, in use, idle or being opened."),
+		maxConns:          desc("max_conns", "Maximum size of the pool."),
+		acquires:          desc("acquires_total", "Connections acquired from the pool."),
+		acquireDuration:   desc("acquire_duration_seconds_total", "Time spent waiting for a connection."),
+		emptyAcquires:     desc("empty_acquires_total", "Acquires that had to wait because no connection was idle."),
+		canceledAcquires:  desc("canceled_acquires_total", "Acquires cancelled by their context, usually a query timeout."),
+		newConns:          desc("new_conns_total", "Connections opened."),
+		lifetimeDestroys:  desc("max_lifetime_destroys_total", "Connections closed for reaching DB_MAX_CONN_LIFETIME."),
+		idleDestroys:      desc("max_idle_destroys_total", "Connections closed for being idle longer than DB_MAX_CONN_IDLE_TIME."),
+	})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+	counter(c.acquires, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.emptyAcquires, float64(stat.EmptyAcquireCount()))
+	counter(c.canceledAcquires, float64(stat.CanceledAcquireCount()))
+	counter(c.newConns, float64(stat.NewConnsCount()))
+	counter(c.lifetimeDestroys, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.idleDestroys, float64(stat.MaxIdleDestroyCount()))
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"attendance-app/internal/helpers"
+	"net/http"
+)
+
+// AccessLog tracks the start time and body of each request so SetResponse
+// can write the access log entry with latency and the redacted body.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, helpers.TrackRequest(r))
+	})
+}
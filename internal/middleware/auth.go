@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/helpers"
+	"attendance-app/internal/logger"
+	"attendance-app/internal/repository"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Authenticate checks the bearer token and reloads the operator on every
+// request, so deactivated or deleted operators are refused immediately even
+// while their token has not expired yet.
+func Authenticate(tokens *auth.TokenIssuer, operators repository.OperatorStore) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || tokenString == "" {
+				helpers.SetResponse(w, r, "Missing authorization token", nil, http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := tokens.ParseToken(tokenString)
+			if err != nil {
+				helpers.SetResponse(w, r, "Invalid or expired token", nil, http.StatusUnauthorized)
+				return
+			}
+
+			operator, err := operators.GetOperatorByID(r.Context(), claims.ID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					helpers.SetResponse(w, r, "Invalid or expired token", nil, http.StatusUnauthorized)
+					return
+				}
+
+				logger.Println(r.Context(), "Error loading operator:", err)
+				helpers.SetResponse(w, r, "Failed to authenticate", nil, http.StatusInternalServerError)
+				return
+			}
+
+			if !operator.IsActive {
+				helpers.SetResponse(w, r, "Your account is inactive. Please contact the administrator for further assistance.", nil, http.StatusForbidden)
+				return
+			}
+
+			// Tokens issued before the last password change are revoked. A
+			// version is exact where issue times only have whole seconds.
+			if claims.TokenVersion != operator.TokenVersion {
+				helpers.SetResponse(w, r, "Your session has expired. Please log in again.", nil, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithOperator(r.Context(), operator)))
+		})
+	}
+}
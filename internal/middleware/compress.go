@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"attendance-app/internal/helpers"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings the API speaks, in order of preference when the client accepts
+// several equally
+var encodings = []string{"zstd", "gzip"}
+
+// Encoders are reused across responses, a zstd encoder is costly to create
+var encoderPools = map[string]*sync.Pool{
+	"zstd": {New: func() interface{} {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return encoder
+	}},
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// Decoded zstd frames may not ask for a larger window than this, so a small
+// request cannot make the server allocate a lot of memory
+const maxZstdWindow = 8 << 20
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress encodes responses with zstd or gzip when the client accepts one of
+// them in Accept-Encoding.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// Decompress decodes request bodies sent with Content-Encoding gzip or zstd
+// and refuses other encodings with 415. Size limits the handler puts on the
+// body apply to the decoded bytes.
+func Decompress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.ReadCloser
+
+		switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+		case "", "identity":
+			next.ServeHTTP(w, r)
+			return
+		case "gzip":
+			reader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				helpers.SetResponse(w, r, "Request body is not valid gzip", nil, http.StatusBadRequest)
+				return
+			}
+			body = reader
+		case "zstd":
+			decoder, err := zstd.NewReader(r.Body, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(maxZstdWindow))
+			if err != nil {
+				helpers.SetResponse(w, r, "Request body is not valid zstd", nil, http.StatusBadRequest)
+				return
+			}
+			body = decoder.IOReadCloser()
+		default:
+			w.Header().Set("Accept-Encoding", strings.Join(encodings, ", "))
+			helpers.SetResponse(w, r, "Unsupported Content-Encoding "+strconv.Quote(encoding), nil, http.StatusUnsupportedMediaType)
+			return
+		}
+		defer body.Close()
+
+		r.Body = body
+		r.ContentLength = -1
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// negotiateEncoding picks the supported encoding the client prefers, or ""
+// to send the response as is.
+func negotiateEncoding(header string) string {
+	qualities := map[string]float64{}
+	wildcard := -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter encodes the body once the handler has written the header.
+// Responses without a body, or already encoded by the handler, pass through.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     encoder
+	wroteHeader bool
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.wroteHeader {
+		return
+	}
+	c.wroteHeader = true
+
+	header := c.Header()
+	if bodyAllowed(status) && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", c.encoding)
+		header.Del("Content-Length")
+
+		c.encoder = encoderPools[c.encoding].Get().(encoder)
+		c.encoder.Reset(c.ResponseWriter)
+	}
+
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	if c.encoder == nil {
+		return c.ResponseWriter.Write(p)
+	}
+
+	return c.encoder.Write(p)
+}
+
+// Flush sends what has been encoded so far.
+func (c *compressWriter) Flush() {
+	if c.encoder != nil {
+		c.encoder.Flush()
+	}
+
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// close ends the encoded stream and returns the encoder to its pool.
+func (c *compressWriter) close() {
+	if c.encoder == nil {
+		return
+	}
+
+	c.encoder.Close()
+	c.encoder.Reset(nil)
+	encoderPools[c.encoding].Put(c.encoder)
+	c.encoder = nil
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import "testing"
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"br", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "gzip"},
+		{"zstd", "zstd"},
+		{"gzip, zstd", "zstd"},
+		{"gzip;q=1.0, zstd;q=0.5", "gzip"},
+		{"ZSTD;q=0.8, gzip;q=0.2", "zstd"},
+		{"zstd;q=0, gzip", "gzip"},
+		{"*", "zstd"},
+		{"*;q=0", ""},
+		{"*, zstd;q=0", "gzip"},
+		{"gzip;q=bad", ""},
+	}
+
+	for _, test := range tests {
+		if got := negotiateEncoding(test.header); got != test.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"attendance-app/internal/auth"
+	"attendance-app/internal/helpers"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RequirePermission refuses requests from operators whose role does not grant
+// the permission. It must run after Authenticate.
+func RequirePermission(permission auth.Permission) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operator, ok := auth.OperatorFromContext(r.Context())
+			if !ok {
+				helpers.SetResponse(w, r, "Missing authorization token", nil, http.StatusUnauthorized)
+				return
+			}
+
+			if !auth.HasPermission(operator, permission) {
+				helpers.SetResponse(w, r, "You do not have permission to perform this action", nil, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
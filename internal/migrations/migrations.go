@@ -0,0 +1,343 @@
+// Package migrations holds the versioned database schema and applies it. Each
+// version is a pair of files in sql/, NNNN_name.up.sql and NNNN_name.down.sql,
+// and the applied versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Any fixed number works, it only keeps two runners from migrating at once
+const advisoryLockID = 720431
+
+// Migration is one schema version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied, nil when pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// ErrSchemaVersion is returned by Check when the database is not at the
+// version the app expects.
+var ErrSchemaVersion = errors.New("database schema version mismatch")
+
+var all = mustLoad()
+
+func mustLoad() []Migration {
+	migrations, err := load(files)
+	if err != nil {
+		panic(err)
+	}
+
+	return migrations
+}
+
+// load reads the migrations in version order and checks that every version
+// has both directions and no version is skipped.
+func load(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, p := range paths {
+		match := fileName.FindStringSubmatch(path.Base(p))
+		if match == nil {
+			return nil, fmt.Errorf("migration %s is not named NNNN_name.up.sql or NNNN_name.down.sql", p)
+		}
+
+		version, _ := strconv.Atoi(match[1])
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Latest is the schema version this build of the app expects.
+func Latest() int {
+	return len(all)
+}
+
+// Runner applies and rolls back migrations on a database.
+type Runner struct {
+	db  *pgxpool.Pool
+	out io.Writer // Progress and, in a dry run, the SQL that would run
+}
+
+// NewRunner returns a runner that reports what it does to out.
+func NewRunner(db *pgxpool.Pool, out io.Writer) *Runner {
+	return &Runner{db: db, out: out}
+}
+
+// Version returns the latest applied version, 0 for an empty database.
+func Version(ctx context.Context, db *pgxpool.Pool) (int, error) {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err := db.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+
+	return version, err
+}
+
+// Check returns ErrSchemaVersion unless the database is at Latest.
+func Check(ctx context.Context, db *pgxpool.Pool) error {
+	version, err := Version(ctx, db)
+	if err != nil {
+		return fmt.Errorf("failed to read the schema version: %w", err)
+	}
+
+	switch {
+	case version < Latest():
+		return fmt.Errorf("%w: database is at version %d, the app needs %d, run the migrate command", ErrSchemaVersion, version, Latest())
+	case version > Latest():
+		return fmt.Errorf("%w: database is at version %d, newer than the %d this build knows", ErrSchemaVersion, version, Latest())
+	}
+
+	return nil
+}
+
+// Status lists every migration and when it was applied.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(all))
+	for i, m := range all {
+		statuses[i] = Status{Migration: m}
+
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+
+	return statuses, nil
+}
+
+// Up applies up to steps pending migrations, all of them when steps is 0.
+// A dry run prints the SQL instead of running it.
+func (r *Runner) Up(ctx context.Context, steps int, dryRun bool) error {
+	current, err := r.version(ctx, dryRun)
+	if err != nil {
+		return err
+	}
+
+	pending := all[current:]
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	if len(pending) == 0 {
+		fmt.Fprintf(r.out, "Database is up to date at version %d\n", current)
+		return nil
+	}
+
+	for _, m := range pending {
+		if err := r.apply(ctx, m, m.Up, true, dryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Down rolls back the last steps applied migrations, at least one.
+func (r *Runner) Down(ctx context.Context, steps int, dryRun bool) error {
+	current, err := r.version(ctx, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if steps < 1 {
+		steps = 1
+	}
+
+	for i := 0; i < steps && current-i > 0; i++ {
+		m := all[current-i-1]
+
+		if err := r.apply(ctx, m, m.Down, false, dryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply runs one direction of a migration and records it in one
+// transaction, so a failing migration leaves no trace.
+func (r *Runner) apply(ctx context.Context, m Migration, script string, up, dryRun bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	if dryRun {
+		fmt.Fprintf(r.out, "-- %04d_%s %s (dry run)\n%s\n", m.Version, m.Name, direction, script)
+		return nil
+	}
+
+	fmt.Fprintf(r.out, "Migrating %04d_%s %s\n", m.Version, m.Name, direction)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, advisoryLockID); err != nil {
+		return err
+	}
+
+	// Another runner may have got here first while we waited for the lock
+	var applied bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+
+	if applied == up {
+		fmt.Fprintf(r.out, "Skipping %04d_%s, already migrated %s\n", m.Version, m.Name, direction)
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return fmt.Errorf("migration %04d_%s %s failed: %w", m.Version, m.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+	} else {
+		_, err = tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// version creates the bookkeeping table when needed and returns the current
+// version, refusing databases newer than this build. A dry run leaves the
+// database untouched and reads a missing table as version 0.
+func (r *Runner) version(ctx context.Context, dryRun bool) (int, error) {
+	if !dryRun {
+		_, err := r.db.Exec(ctx, `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				applied_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'utc')
+			)`)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	current, err := Version(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+
+	if current > Latest() {
+		return 0, fmt.Errorf("%w: database is at version %d, newer than the %d this build knows", ErrSchemaVersion, current, Latest())
+	}
+
+	return current, nil
+}
+
+func (r *Runner) applied(ctx context.Context) (map[int]time.Time, error) {
+	applied := map[int]time.Time{}
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+
+		applied[version] = at
+	}
+
+	return applied, rows.Err()
+}
@@ -0,0 +1,175 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	if Latest() == 0 {
+		t.Fatal("no migrations are embedded")
+	}
+
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d", i+1, m.Version)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(content string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(content)}
+	}
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "valid",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":     file("CREATE TABLE a (id INT);"),
+				"sql/0001_init.down.sql":   file("DROP TABLE a;"),
+				"sql/0002_second.up.sql":   file("CREATE TABLE b (id INT);"),
+				"sql/0002_second.down.sql": file("DROP TABLE b;"),
+			},
+		},
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql": file("CREATE TABLE a (id INT);"),
+			},
+			wantErr: "needs both an up and a down file",
+		},
+		{
+			name: "gap",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":    file("CREATE TABLE a (id INT);"),
+				"sql/0001_init.down.sql":  file("DROP TABLE a;"),
+				"sql/0003_third.up.sql":   file("CREATE TABLE c (id INT);"),
+				"sql/0003_third.down.sql": file("DROP TABLE c;"),
+			},
+			wantErr: "migration 2 is missing",
+		},
+		{
+			name: "bad name",
+			files: fstest.MapFS{
+				"sql/init.sql": file("CREATE TABLE a (id INT);"),
+			},
+			wantErr: "is not named",
+		},
+		{
+			name: "two names",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":    file("CREATE TABLE a (id INT);"),
+				"sql/0001_other.down.sql": file("DROP TABLE a;"),
+			},
+			wantErr: "has two names",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(migrations) != 2 || migrations[1].Name != "second" {
+					t.Errorf("migrations = %+v", migrations)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// The first two versions adopt tables that hold live data, so their downs
+// must never drop anything.
+func TestAdoptedSchemaIsNeverDropped(t *testing.T) {
+	drop := regexp.MustCompile(`(?im)^\s*DROP\s`)
+
+	for _, m := range all[:2] {
+		if drop.MatchString(m.Down) {
+			t.Errorf("%04d_%s down drops tables:\n%s", m.Version, m.Name, m.Down)
+		}
+	}
+}
+
+// emptySchema connects to TEST_DATABASE_URL with a fresh schema first on the
+// search path, dropped again when the test ends.
+func emptySchema(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+func TestDryRunLeavesDatabaseUntouched(t *testing.T) {
+	pool := emptySchema(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	if err := NewRunner(pool, &out).Up(ctx, 0, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "0001_base_schema up (dry run)") {
+		t.Errorf("dry run printed:\n%s", out.String())
+	}
+
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Error("dry run created schema_migrations")
+	}
+}
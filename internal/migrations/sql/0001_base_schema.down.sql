@@ -0,0 +1,4 @@
+-- Does nothing on purpose. The up adopts databases restored from db.sql, whose
+-- tables hold the tickets and users of live events, so rolling it back only
+-- forgets the version and never drops them.
+SELECT 1;
@@ -0,0 +1,91 @@
+-- Events, tickets and scanner operators, as in db.sql. Every statement is
+-- idempotent so databases restored from db.sql can adopt the migrations.
+
+CREATE TABLE IF NOT EXISTS m_users (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS m_events (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS m_classes (
+    id SERIAL PRIMARY KEY,
+    event_id INT NOT NULL REFERENCES m_events(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS m_operators (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_transactions (
+    id SERIAL PRIMARY KEY,
+    amount NUMERIC(10, 2) NOT NULL,
+    transaction_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    invoice_code VARCHAR(50) UNIQUE NOT NULL,
+    transaction_status VARCHAR(50) NOT NULL,
+    user_id INT NOT NULL REFERENCES m_users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_operators_classes (
+    id SERIAL PRIMARY KEY,
+    class_id INT NOT NULL REFERENCES m_classes(id) ON DELETE CASCADE,
+    operator_id INT NOT NULL REFERENCES m_operators(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL,
+    UNIQUE (class_id, operator_id)
+);
+
+CREATE TABLE IF NOT EXISTS t_transaction_details (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES t_transactions(id) ON DELETE CASCADE,
+    class_id INT NOT NULL REFERENCES m_classes(id) ON DELETE CASCADE,
+    ticket_code VARCHAR(100) UNIQUE NOT NULL,
+    attend_status BOOLEAN NOT NULL DEFAULT FALSE,
+    attend_time TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    deleted_at TIMESTAMP DEFAULT NULL,
+    attend_operator_id INT DEFAULT NULL REFERENCES m_operators(id) ON DELETE CASCADE,
+    latest_sync_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON m_users(email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone ON m_users(phone);
+CREATE INDEX IF NOT EXISTS idx_classes_event_id ON m_classes(event_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operators_email ON m_operators(email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operators_phone ON m_operators(phone);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON t_transactions(user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_invoice_code ON t_transactions(invoice_code);
+CREATE INDEX IF NOT EXISTS idx_transactions_status_date ON t_transactions(transaction_status, transaction_date);
+CREATE INDEX IF NOT EXISTS idx_transaction_details_transaction_id ON t_transaction_details(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_details_class_id ON t_transaction_details(class_id);
+CREATE INDEX IF NOT EXISTS idx_operators_classes_operator_id ON t_operators_classes(operator_id);
+CREATE INDEX IF NOT EXISTS idx_operators_classes_class_id ON t_operators_classes(class_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operators_classes_composite ON t_operators_classes(operator_id, class_id);
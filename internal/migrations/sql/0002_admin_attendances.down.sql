@@ -0,0 +1,3 @@
+-- Does nothing on purpose. The up adopts the accounts the admin panel created,
+-- so rolling it back only forgets the version and never drops them.
+SELECT 1;
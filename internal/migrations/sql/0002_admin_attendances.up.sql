@@ -0,0 +1,59 @@
+-- Accounts that log in to the app, their roles and the events and classes
+-- they may see. These were created by the admin panel and are missing from
+-- db.sql, so the statements are idempotent for databases that have them.
+
+CREATE TABLE IF NOT EXISTS m_admin_attendance_roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS m_admin_attendances (
+    id SERIAL PRIMARY KEY,
+    role_id INT DEFAULT NULL REFERENCES m_admin_attendance_roles(id),
+    name VARCHAR(255) NOT NULL,
+    username VARCHAR(255) UNIQUE NOT NULL,
+    email VARCHAR(255) UNIQUE DEFAULT NULL,
+    phone VARCHAR(20) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    is_limited_event_access BOOLEAN NOT NULL DEFAULT FALSE,
+    is_limited_classes_access BOOLEAN NOT NULL DEFAULT FALSE,
+    device_id VARCHAR(255) DEFAULT NULL,
+    device_access_token TEXT DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_list_show_events_admin (
+    id SERIAL PRIMARY KEY,
+    admin_attendance_id INT NOT NULL REFERENCES m_admin_attendances(id) ON DELETE CASCADE,
+    event_id INT NOT NULL REFERENCES m_events(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS t_list_show_classes_admin (
+    id SERIAL PRIMARY KEY,
+    admin_attendance_id INT NOT NULL REFERENCES m_admin_attendances(id) ON DELETE CASCADE,
+    class_id INT NOT NULL REFERENCES m_classes(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_attendances_role_id ON m_admin_attendances(role_id);
+CREATE INDEX IF NOT EXISTS idx_list_show_events_admin_admin_id ON t_list_show_events_admin(admin_attendance_id);
+CREATE INDEX IF NOT EXISTS idx_list_show_classes_admin_admin_id ON t_list_show_classes_admin(admin_attendance_id);
+
+-- The roles the app knows, see internal/auth/permissions.go
+INSERT INTO m_admin_attendance_roles (name)
+SELECT new_role.name
+FROM (VALUES ('Scanner'), ('Supervisor'), ('Event Admin'), ('Super Admin')) AS new_role(name)
+WHERE NOT EXISTS (
+    SELECT 1 FROM m_admin_attendance_roles r WHERE lower(r.name) = lower(new_role.name)
+);
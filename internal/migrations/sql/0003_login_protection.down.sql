@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS t_admin_attendance_login_attempts;
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS failed_login_attempts;
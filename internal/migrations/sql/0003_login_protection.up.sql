@@ -0,0 +1,16 @@
+-- Login brute-force protection
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS t_admin_attendance_login_attempts (
+    id SERIAL PRIMARY KEY,
+    admin_attendance_id INT DEFAULT NULL,
+    username VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent TEXT,
+    reason VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username ON t_admin_attendance_login_attempts(username, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_address ON t_admin_attendance_login_attempts(ip_address, created_at);
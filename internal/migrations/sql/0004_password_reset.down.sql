@@ -0,0 +1,3 @@
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS password_reset_expires_at;
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS password_reset_code;
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS password_changed_at;
@@ -0,0 +1,4 @@
+-- Password change and admin reset
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS password_reset_code VARCHAR(255) DEFAULT NULL;
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS password_reset_expires_at TIMESTAMP DEFAULT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE t_transaction_details ALTER COLUMN latest_sync_at TYPE TIMESTAMP USING latest_sync_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE t_transaction_details ALTER COLUMN attend_time TYPE TIMESTAMP USING attend_time AT TIME ZONE 'UTC';
+
+UPDATE t_transaction_details a
+SET attend_time = (a.attend_time AT TIME ZONE 'UTC') AT TIME ZONE g.timezone
+FROM m_classes f
+JOIN m_events g ON g.id = f.event_id
+WHERE f.id = a.class_id AND a.attend_time IS NOT NULL;
+
+ALTER TABLE m_events DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,15 @@
+-- Attendance times are stored in UTC and shown in the time zone of their
+-- event. Scanners used to send wall-clock times without an offset, so the
+-- times already stored are read as local to their event.
+ALTER TABLE m_events ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta';
+
+UPDATE t_transaction_details a
+SET attend_time = (a.attend_time AT TIME ZONE g.timezone) AT TIME ZONE 'UTC'
+FROM m_classes f
+JOIN m_events g ON g.id = f.event_id
+WHERE f.id = a.class_id AND a.attend_time IS NOT NULL;
+
+ALTER TABLE t_transaction_details ALTER COLUMN attend_time TYPE TIMESTAMPTZ USING attend_time AT TIME ZONE 'UTC';
+
+-- latest_sync_at was filled by now() in the session time zone
+ALTER TABLE t_transaction_details ALTER COLUMN latest_sync_at TYPE TIMESTAMPTZ USING latest_sync_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1 @@
+ALTER TABLE m_admin_attendances DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,6 @@
+-- Sessions carry the version they were issued for, a password change bumps it
+ALTER TABLE m_admin_attendances ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+
+-- Tokens issued before this migration carry no version, revoke them for
+-- operators who already changed their password
+UPDATE m_admin_attendances SET token_version = 1 WHERE password_changed_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE t_transaction_details DROP CONSTRAINT IF EXISTS t_transaction_details_attend_operator_id_fkey;
+ALTER TABLE t_transaction_details ADD CONSTRAINT t_transaction_details_attend_operator_id_fkey
+    FOREIGN KEY (attend_operator_id) REFERENCES m_operators(id) ON DELETE CASCADE NOT VALID;
@@ -0,0 +1,7 @@
+-- Check-ins record the account that uploaded them, which logs in from
+-- m_admin_attendances. Rows written before all name operator 1 of
+-- m_operators and are left unchecked. Removing an account keeps its
+-- check-ins.
+ALTER TABLE t_transaction_details DROP CONSTRAINT IF EXISTS t_transaction_details_attend_operator_id_fkey;
+ALTER TABLE t_transaction_details ADD CONSTRAINT t_transaction_details_attend_operator_id_fkey
+    FOREIGN KEY (attend_operator_id) REFERENCES m_admin_attendances(id) ON DELETE SET NULL NOT VALID;
@@ -1,6 +1,11 @@
 package models
 
-import "github.com/golang-jwt/jwt"
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
 
 type Operator struct {
 	ID                   int           `json:"id,omitempty"`
@@ -14,9 +19,29 @@ type Operator struct {
 	IsLimitedClassAccess bool          `json:"is_limited_classes_access"`
 	DeviceId             *string       `json:"device_id"`
 	DeviceAccessToken    *string       `json:"device_access_token"`
-	Password             string        `json:"password"`
+	Password             string        `json:"password,omitempty"`
 	PasswordConfirmation string        `json:"password_confirmation,omitempty"`
 	AccessEvents         []AccessEvent `json:"access_events,omitempty"`
+	FailedLoginAttempts  int           `json:"failed_login_attempts"`
+	LockedUntil          *time.Time    `json:"locked_until"`
+	PasswordChangedAt    *time.Time    `json:"password_changed_at"`
+	TokenVersion         int           `json:"-"` // Bumped to revoke every session of the operator
+}
+
+// String describes the operator without the password hash, device token or
+// contact details, so an operator can be logged safely.
+func (o Operator) String() string {
+	role := "<none>"
+	if o.Role.Name != nil {
+		role = *o.Role.Name
+	}
+
+	return fmt.Sprintf("Operator{ID: %d, UserName: %q, Role: %q, IsActive: %t}", o.ID, o.UserName, role, o.IsActive)
+}
+
+// GoString keeps %#v from printing the secret fields.
+func (o Operator) GoString() string {
+	return o.String()
 }
 
 type EventClass struct {
@@ -37,18 +62,62 @@ type Role struct {
 
 type RegisterValidationErrors struct {
 	NameError                 string `json:"name,omitempty"`
+	UserNameError             string `json:"username,omitempty"`
 	EmailError                string `json:"email,omitempty"`
 	PhoneError                string `json:"phone,omitempty"`
 	PasswordError             string `json:"password,omitempty"`
 	PasswordConfirmationError string `json:"password_confirmation_error,omitempty"`
+	RoleError                 string `json:"role_id,omitempty"`
+	ClassesError              string `json:"class_ids,omitempty"`
+}
+
+// HasErrors reports whether any field failed validation.
+func (e RegisterValidationErrors) HasErrors() bool {
+	return e != RegisterValidationErrors{}
+}
+
+// PasswordReset is the pending one-time password reset code of an operator.
+type PasswordReset struct {
+	OperatorID int        `json:"operator_id"`
+	CodeHash   *string    `json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// PasswordResetCode is returned once to the admin who issued the reset.
+type PasswordResetCode struct {
+	OperatorID int       `json:"operator_id"`
+	Code       string    `json:"code"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
 
 type Claims struct {
-	Username string `json:"username"`
+	ID           int    `json:"id"`
+	Username     string `json:"username"`
+	TokenVersion int    `json:"ver"`
 	jwt.StandardClaims
 }
 
 type LoginResponseData struct {
-	Token string   `json:"token"`
-	User  Operator `json:"user"`
+	Token       string   `json:"token"`
+	User        Operator `json:"user"`
+	Permissions []string `json:"permissions"`
+}
+
+// LoginAttempt is an entry in the login audit log.
+type LoginAttempt struct {
+	OperatorID *int   `json:"operator_id"`
+	UserName   string `json:"username"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	Reason     string `json:"reason"`
+}
+
+// LoginLockout is a username, IP address or operator account that is
+// temporarily refused because of failed logins.
+type LoginLockout struct {
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	OperatorID  *int      `json:"operator_id,omitempty"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOperatorStringHidesSecrets(t *testing.T) {
+	roleName := "scanner"
+	email := "gate1@example.com"
+	token := "eyJhbGciOiJIUzI1NiJ9.secret"
+
+	operator := Operator{
+		ID:                7,
+		Role:              Role{Name: &roleName},
+		UserName:          "gate1",
+		Email:             &email,
+		Phone:             "08123456789",
+		DeviceAccessToken: &token,
+		Password:          "$2a$12$hash",
+		IsActive:          true,
+	}
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		logged := fmt.Sprintf(format, operator)
+
+		for _, secret := range []string{"$2a$12$hash", token, email, "08123456789"} {
+			if strings.Contains(logged, secret) {
+				t.Errorf("%s of operator %q contains %q", format, logged, secret)
+			}
+		}
+
+		if !strings.Contains(logged, "gate1") {
+			t.Errorf("%s of operator %q does not identify the operator", format, logged)
+		}
+	}
+
+	if logged := fmt.Sprint(&operator); strings.Contains(logged, "$2a$12$hash") {
+		t.Errorf("pointer to operator logged as %q", logged)
+	}
+}
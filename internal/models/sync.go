@@ -1,12 +1,14 @@
 package models
 
 import (
+	"attendance-app/internal/tickets"
 	"time"
 )
 
 type Event struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	TimeZone string `json:"timezone"` // IANA name such as Asia/Jakarta, attend times are shown in it
 }
 
 type User struct {
@@ -32,6 +34,76 @@ type SyncResponse struct {
 	Class        Class      `json:"class"`
 }
 
+// SyncDownload is the normalized form of a sync download. Events, classes and
+// users are listed once and the tickets refer to them by ID, instead of every
+// ticket repeating them as in SyncResponse.
+type SyncDownload struct {
+	Events  []Event      `json:"events"`
+	Classes []SyncClass  `json:"classes"`
+	Users   []User       `json:"users"`
+	Tickets []SyncTicket `json:"tickets"`
+}
+
+type SyncClass struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	EventID int    `json:"event_id"`
+}
+
+type SyncTicket struct {
+	ID           int        `json:"id"`
+	InvoiceCode  string     `json:"invoice_code"`
+	TicketCode   string     `json:"ticket_code"`
+	AttendStatus bool       `json:"attend_status"`
+	AttendTime   *time.Time `json:"attend_time"`
+	ClassID      int        `json:"class_id"`
+	UserID       int        `json:"user_id"`
+}
+
+// NormalizeSync turns the rows of a sync download into a SyncDownload,
+// keeping the order in which events, classes and users first appear.
+func NormalizeSync(rows []SyncResponse) SyncDownload {
+	download := SyncDownload{
+		Events:  []Event{},
+		Classes: []SyncClass{},
+		Users:   []User{},
+		Tickets: make([]SyncTicket, 0, len(rows)),
+	}
+
+	events := map[int]bool{}
+	classes := map[int]bool{}
+	users := map[int]bool{}
+
+	for _, row := range rows {
+		if event := row.Class.Event; !events[event.ID] {
+			events[event.ID] = true
+			download.Events = append(download.Events, event)
+		}
+
+		if class := row.Class; !classes[class.ID] {
+			classes[class.ID] = true
+			download.Classes = append(download.Classes, SyncClass{ID: class.ID, Name: class.Name, EventID: class.Event.ID})
+		}
+
+		if user := row.User; !users[user.ID] {
+			users[user.ID] = true
+			download.Users = append(download.Users, user)
+		}
+
+		download.Tickets = append(download.Tickets, SyncTicket{
+			ID:           row.ID,
+			InvoiceCode:  row.InvoiceCode,
+			TicketCode:   row.TicketCode,
+			AttendStatus: row.AttendStatus,
+			AttendTime:   row.AttendTime,
+			ClassID:      row.Class.ID,
+			UserID:       row.User.ID,
+		})
+	}
+
+	return download
+}
+
 type SyncData struct {
 	InvoiceCode string `json:"invoice_code"`
 	AttendTime  string `json:"attend_time"`
@@ -45,3 +117,24 @@ type SyncDataUpdate struct {
 type SyncPayload struct {
 	Data []SyncData `json:"data"`
 }
+
+// AttendanceVersion identifies the state of the attendance data an operator
+// downloads. It changes whenever the data does.
+type AttendanceVersion struct {
+	Tickets   int
+	UpdatedAt *time.Time // Latest change to the tickets, their transactions, users, classes and events
+}
+
+// SyncMeta is sent next to the attendance data of a sync download.
+type SyncMeta struct {
+	TicketVerificationKey *tickets.VerificationKey `json:"ticket_verification_key,omitempty"`
+}
+
+// SignedTicket is a ticket QR payload that scanners can verify offline.
+type SignedTicket struct {
+	TicketCode string    `json:"ticket_code"`
+	ClassID    int       `json:"class_id"`
+	ValidFrom  time.Time `json:"valid_from"`
+	ValidUntil time.Time `json:"valid_until"`
+	Payload    string    `json:"payload"`
+}
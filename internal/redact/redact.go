@@ -0,0 +1,86 @@
+// Package redact removes passwords, tokens and personal data from values
+// before they are written to logs.
+package redact
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+// Field names, compared in lower case, whose values are never logged
+var sensitiveFields = map[string]bool{
+	// Secrets
+	"password":              true,
+	"password_confirmation": true,
+	"old_password":          true,
+	"code":                  true,
+	"token":                 true,
+	"access_token":          true,
+	"refresh_token":         true,
+	"device_access_token":   true,
+	"secret":                true,
+
+	// Personal data
+	"email": true,
+	"phone": true,
+}
+
+// Headers that carry credentials
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Metrics-Token":     true,
+}
+
+// IsSensitiveField reports whether a field with this name must not be logged.
+func IsSensitiveField(name string) bool {
+	return sensitiveFields[strings.ToLower(name)]
+}
+
+// Headers returns a copy of the headers that is safe to log, with credentials
+// replaced by the placeholder.
+func Headers(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+
+	safe := make(map[string]string, len(header))
+
+	for name, values := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			safe[name] = Placeholder
+			continue
+		}
+
+		safe[name] = strings.Join(values, ", ")
+	}
+
+	return safe
+}
+
+// JSON redacts the sensitive fields of a decoded JSON value in place, at any
+// depth, and returns it.
+func JSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if IsSensitiveField(key) {
+				v[key] = Placeholder
+				continue
+			}
+			v[key] = JSON(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = JSON(item)
+		}
+	}
+
+	return value
+}
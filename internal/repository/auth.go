@@ -1,30 +1,135 @@
 package repository
 
 import (
-	db "attendance-app/internal/database"
 	"attendance-app/internal/models"
+	"context"
 	"database/sql"
-	"encoding/json"
-	"log"
+	"errors"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrOperatorExists = errors.New("operator_exists")
+	ErrClassNotFound  = errors.New("class_not_found")
 )
 
-func InsertOperator(name, email, phone, hashedPassword string) (int, error) {
+// InsertOperator inserts an active operator inside the given transaction.
+func (s *Store) InsertOperator(ctx context.Context, tx pgx.Tx, operator *models.Operator, hashedPassword string) (int, error) {
 	query := `
-		INSERT INTO m_operators (name, email, phone, password, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO m_admin_attendances (
+			role_id, name, username, email, phone, password,
+			is_active, is_limited_event_access, is_limited_classes_access,
+			created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, true, $7, $8, $9, $9)
 		RETURNING id`
 
 	var id int
-	err := db.DB.QueryRow(query, name, email, phone, hashedPassword, time.Now(), time.Now()).Scan(&id)
+	err := tx.QueryRow(ctx, query,
+		operator.Role.ID,
+		operator.Name,
+		operator.UserName,
+		operator.Email,
+		operator.Phone,
+		hashedPassword,
+		operator.IsLimitedEventAccess,
+		operator.IsLimitedClassAccess,
+		time.Now(),
+	).Scan(&id)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			return 0, ErrOperatorExists
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// CreateOperator inserts the operator together with access to the given
+// classes and their events in one transaction.
+func (s *Store) CreateOperator(ctx context.Context, operator *models.Operator, hashedPassword string, classIDs []int) (int, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	operator.IsLimitedClassAccess = len(classIDs) > 0
+	operator.IsLimitedEventAccess = len(classIDs) > 0
 
+	id, err := s.InsertOperator(ctx, tx, operator, hashedPassword)
 	if err != nil {
 		return 0, err
 	}
+
+	if len(classIDs) > 0 {
+		// All requested classes must exist
+		var found int
+		err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM m_classes WHERE id = ANY($1) AND deleted_at IS NULL`, classIDs).Scan(&found)
+		if err != nil {
+			return 0, err
+		}
+
+		if found != len(classIDs) {
+			return 0, ErrClassNotFound
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO t_list_show_events_admin (admin_attendance_id, event_id, created_at, updated_at)
+			SELECT DISTINCT $1::int, event_id, now(), now()
+			FROM m_classes
+			WHERE id = ANY($2)`, id, classIDs)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO t_list_show_classes_admin (admin_attendance_id, class_id, created_at, updated_at)
+			SELECT $1::int, id, now(), now()
+			FROM m_classes
+			WHERE id = ANY($2)`, id, classIDs)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	operator.ID = id
+
 	return id, nil
 }
 
-func UpdateOperatorDeviceInformation(device_id *string, device_token *string, operator_id int) error {
+// GetRole fetches a role from m_admin_attendance_roles.
+func (s *Store) GetRole(ctx context.Context, roleID int) (*models.Role, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var result models.Role
+
+	err := s.db.QueryRow(ctx, `SELECT id, name FROM m_admin_attendance_roles WHERE id = $1`, roleID).
+		Scan(&result.ID, &result.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *Store) UpdateOperatorDeviceInformation(ctx context.Context, deviceID *string, deviceToken *string, operatorID int) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
 	// Prepare the base query
 	query := `
 		UPDATE m_admin_attendances 
@@ -34,7 +139,7 @@ func UpdateOperatorDeviceInformation(device_id *string, device_token *string, op
 			updated_at = now()
 		WHERE id = $3`
 
-	_, err := db.DB.Exec(query, device_id, device_token, operator_id)
+	_, err := s.db.Exec(ctx, query, deviceID, deviceToken, operatorID)
 
 	if err != nil {
 		return err
@@ -43,18 +148,187 @@ func UpdateOperatorDeviceInformation(device_id *string, device_token *string, op
 	return nil
 }
 
-func OperatorExists(email string, phone string) bool {
-	var id int
-	err := db.DB.QueryRow("SELECT id FROM m_operators WHERE email = $1 or phone = $2", email, phone).Scan(&id)
+// RecordOperatorLoginFailure increments the failed login counter of an operator
+// and locks the account until lockedUntil when it is set. Times are stored in
+// UTC so they compare correctly with Go time regardless of the server zone.
+func (s *Store) RecordOperatorLoginFailure(ctx context.Context, operatorID int, lockedUntil *time.Time) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	if lockedUntil != nil {
+		utc := lockedUntil.UTC()
+		lockedUntil = &utc
+	}
+
+	query := `
+		UPDATE m_admin_attendances
+		SET
+			failed_login_attempts = failed_login_attempts + 1,
+			locked_until = COALESCE($1, locked_until),
+			updated_at = now()
+		WHERE id = $2`
 
-	return err == nil // If no error, operator exists
+	_, err := s.db.Exec(ctx, query, lockedUntil, operatorID)
+
+	return err
 }
 
-func GetOperatorByUsername(username string) (*models.Operator, error) {
-	var result models.Operator
+// ResetOperatorLoginFailures clears the failed login counter and any lockout.
+func (s *Store) ResetOperatorLoginFailures(ctx context.Context, operatorID int) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE m_admin_attendances
+		SET
+			failed_login_attempts = 0,
+			locked_until = NULL,
+			updated_at = now()
+		WHERE id = $1`
+
+	_, err := s.db.Exec(ctx, query, operatorID)
+
+	return err
+}
+
+// UpdateOperatorPassword stores a new password hash, consumes any pending reset
+// code and revokes existing sessions. Tokens carrying an older token_version
+// are refused by the authentication middleware.
+func (s *Store) UpdateOperatorPassword(ctx context.Context, operatorID int, hashedPassword string) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE m_admin_attendances
+		SET
+			password = $1,
+			password_changed_at = $2,
+			token_version = token_version + 1,
+			password_reset_code = NULL,
+			password_reset_expires_at = NULL,
+			device_access_token = NULL,
+			updated_at = now()
+		WHERE id = $3`
+
+	_, err := s.db.Exec(ctx, query, hashedPassword, time.Now().UTC(), operatorID)
+
+	return err
+}
+
+// SetOperatorPasswordResetCode stores the hash of a one-time reset code.
+func (s *Store) SetOperatorPasswordResetCode(ctx context.Context, operatorID int, codeHash string, expiresAt time.Time) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE m_admin_attendances
+		SET
+			password_reset_code = $1,
+			password_reset_expires_at = $2,
+			updated_at = now()
+		WHERE id = $3 AND deleted_at IS NULL`
+
+	result, err := s.db.Exec(ctx, query, codeHash, expiresAt.UTC(), operatorID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetOperatorPasswordReset fetches the pending reset code of an operator.
+func (s *Store) GetOperatorPasswordReset(ctx context.Context, username string) (*models.PasswordReset, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var result models.PasswordReset
+
+	query := `
+		SELECT id, password_reset_code, password_reset_expires_at
+		FROM m_admin_attendances
+		WHERE username = $1 AND deleted_at IS NULL`
+
+	err := s.db.QueryRow(ctx, query, username).Scan(&result.OperatorID, &result.CodeHash, &result.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetLockedOperators lists operator accounts that are currently locked out.
+func (s *Store) GetLockedOperators(ctx context.Context) ([]models.LoginLockout, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, username, failed_login_attempts, locked_until
+		FROM m_admin_attendances
+		WHERE locked_until > $1 AND deleted_at IS NULL
+		ORDER BY locked_until DESC`
+
+	rows, err := s.db.Query(ctx, query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lockouts []models.LoginLockout
+
+	for rows.Next() {
+		var operatorID int
+		lockout := models.LoginLockout{Scope: "operator"}
+
+		if err := rows.Scan(&operatorID, &lockout.Key, &lockout.Failures, &lockout.LockedUntil); err != nil {
+			return nil, err
+		}
+
+		lockout.OperatorID = &operatorID
+		lockouts = append(lockouts, lockout)
+	}
+
+	return lockouts, rows.Err()
+}
+
+// InsertLoginAttempt writes a failed or refused login to the audit log.
+func (s *Store) InsertLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
 
-	// Main query for the operator and role details
 	query := `
+		INSERT INTO t_admin_attendance_login_attempts (admin_attendance_id, username, ip_address, user_agent, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, now())`
+
+	_, err := s.db.Exec(ctx, query, attempt.OperatorID, attempt.UserName, attempt.IPAddress, attempt.UserAgent, attempt.Reason)
+
+	return err
+}
+
+// OperatorExists reports which of the username, email and phone already
+// belong to an operator.
+func (s *Store) OperatorExists(ctx context.Context, username string, email *string, phone string) (usernameTaken, emailTaken, phoneTaken bool, err error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			COALESCE(bool_or(username = $1), false),
+			COALESCE(bool_or(email = $2), false),
+			COALESCE(bool_or(phone = $3), false)
+		FROM m_admin_attendances
+		WHERE username = $1 OR email = $2 OR phone = $3`
+
+	err = s.db.QueryRow(ctx, query, username, email, phone).Scan(&usernameTaken, &emailTaken, &phoneTaken)
+
+	return usernameTaken, emailTaken, phoneTaken, err
+}
+
+// operatorQuery selects the operator and role details shared by the operator lookups.
+const operatorQuery = `
 		SELECT 
 			a.id, 
 			a.name, 
@@ -67,29 +341,65 @@ func GetOperatorByUsername(username string) (*models.Operator, error) {
 			a.device_id, 
 			a.device_access_token, 
 			a.password,
+			a.failed_login_attempts,
+			a.locked_until,
+			a.password_changed_at,
+			a.token_version,
 			b.id as role_id,
 			b.name as role_name
 		FROM m_admin_attendances a
-		LEFT JOIN m_admin_attendance_roles b ON b.id = a.role_id
-		WHERE a.username = $1`
-
-	// Fetch the main operator details
-	err := db.DB.QueryRow(query, username).
-		Scan(
-			&result.ID,
-			&result.Name,
-			&result.UserName,
-			&result.Email,
-			&result.Phone,
-			&result.IsActive,
-			&result.IsLimitedEventAccess,
-			&result.IsLimitedClassAccess,
-			&result.DeviceId,
-			&result.DeviceAccessToken,
-			&result.Password,
-			&result.Role.ID,
-			&result.Role.Name,
-		)
+		LEFT JOIN m_admin_attendance_roles b ON b.id = a.role_id`
+
+func scanOperator(row pgx.Row, result *models.Operator) error {
+	return row.Scan(
+		&result.ID,
+		&result.Name,
+		&result.UserName,
+		&result.Email,
+		&result.Phone,
+		&result.IsActive,
+		&result.IsLimitedEventAccess,
+		&result.IsLimitedClassAccess,
+		&result.DeviceId,
+		&result.DeviceAccessToken,
+		&result.Password,
+		&result.FailedLoginAttempts,
+		&result.LockedUntil,
+		&result.PasswordChangedAt,
+		&result.TokenVersion,
+		&result.Role.ID,
+		&result.Role.Name,
+	)
+}
+
+// GetOperatorByID fetches an operator without the event access details. Soft
+// deleted operators are treated as missing.
+func (s *Store) GetOperatorByID(ctx context.Context, operatorID int) (*models.Operator, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var result models.Operator
+
+	err := scanOperator(s.db.QueryRow(ctx, operatorQuery+`
+		WHERE a.id = $1 AND a.deleted_at IS NULL`, operatorID), &result)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (s *Store) GetOperatorByUsername(ctx context.Context, username string) (*models.Operator, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	var result models.Operator
+
+	// Fetch the main operator details, soft deleted operators cannot log in
+	err := scanOperator(s.db.QueryRow(ctx, operatorQuery+`
+		WHERE a.username = $1 AND a.deleted_at IS NULL`, username), &result)
+
 	if err != nil {
 		return nil, err // Handle error appropriately
 	}
@@ -99,9 +409,9 @@ func GetOperatorByUsername(username string) (*models.Operator, error) {
 		eagerQuery := `
 			SELECT 
 				a.event_id,
-				d.event_name,
+				d.name,
 				b.class_id,
-				c.class_name
+				c.name
 			FROM t_list_show_events_admin a
 			LEFT JOIN (
 				SELECT 
@@ -115,7 +425,7 @@ func GetOperatorByUsername(username string) (*models.Operator, error) {
 			LEFT JOIN m_events d ON d.id = a.event_id
 			WHERE a.admin_attendance_id = $1 and a.deleted_at is null`
 
-		rows, err := db.DB.Query(eagerQuery, result.ID)
+		rows, err := s.db.Query(ctx, eagerQuery, result.ID)
 
 		if err != nil {
 			return nil, err // Handle error appropriately
@@ -176,14 +486,5 @@ func GetOperatorByUsername(username string) (*models.Operator, error) {
 		result.AccessEvents = accessEvents
 	}
 
-	log.Println(result)
-
-	jsonResult, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		log.Printf("Error marshalling to JSON: %v", err)
-	}
-
-	log.Println(string(jsonResult))
-
 	return &result, nil
 }
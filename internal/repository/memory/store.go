@@ -0,0 +1,504 @@
+// Package memory is an in-memory implementation of the repository stores for
+// tests. It keeps the behaviour handlers rely on, such as sql.ErrNoRows for
+// missing rows and the repository errors, without needing Postgres.
+package memory
+
+import (
+	"attendance-app/internal/models"
+	"attendance-app/internal/repository"
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Ticket is a ticket as the sync endpoints see it.
+type Ticket struct {
+	ID               int
+	InvoiceCode      string
+	TicketCode       string
+	ClassID          int
+	User             models.User
+	AttendStatus     bool
+	AttendTime       *time.Time
+	AttendOperatorID *int
+
+	updatedAt time.Time
+}
+
+// Store holds operators and tickets in maps guarded by a mutex.
+type Store struct {
+	mu sync.Mutex
+
+	roles     map[int]models.Role
+	operators map[int]*models.Operator
+	resets    map[int]models.PasswordReset
+	attempts  []models.LoginAttempt
+
+	classes  map[int]models.Class
+	scanners map[int][]int // Scanner operator ID to the classes it scans
+	tickets  map[string]*Ticket
+
+	nextOperatorID int
+}
+
+var (
+	_ repository.OperatorStore   = (*Store)(nil)
+	_ repository.AttendanceStore = (*Store)(nil)
+)
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{
+		roles:          map[int]models.Role{},
+		operators:      map[int]*models.Operator{},
+		resets:         map[int]models.PasswordReset{},
+		classes:        map[int]models.Class{},
+		scanners:       map[int][]int{},
+		tickets:        map[string]*Ticket{},
+		nextOperatorID: 1,
+	}
+}
+
+// AddRole stores a role that operators can be created with.
+func (s *Store) AddRole(role models.Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.roles[*role.ID] = role
+}
+
+// AddOperator stores an operator with the given password hash and returns it
+// with its assigned ID.
+func (s *Store) AddOperator(operator models.Operator, hashedPassword string) models.Operator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operator.ID = s.nextOperatorID
+	operator.Password = hashedPassword
+	s.nextOperatorID++
+
+	stored := operator
+	s.operators[operator.ID] = &stored
+
+	return operator
+}
+
+// AddClass stores a class that tickets and operators can refer to.
+func (s *Store) AddClass(class models.Class) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.classes[class.ID] = class
+}
+
+// AddScanner stores a scanner operator with access to the given classes.
+func (s *Store) AddScanner(operatorID int, classIDs ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scanners[operatorID] = append(s.scanners[operatorID], classIDs...)
+}
+
+// AddTicket stores a ticket.
+func (s *Store) AddTicket(ticket Ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ticket.updatedAt = time.Now()
+	s.tickets[ticket.TicketCode] = &ticket
+}
+
+// Ticket returns a copy of the stored ticket.
+func (s *Store) Ticket(ticketCode string) (Ticket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ticket, ok := s.tickets[ticketCode]
+	if !ok {
+		return Ticket{}, false
+	}
+
+	return *ticket, true
+}
+
+// LoginAttempts returns the login audit log.
+func (s *Store) LoginAttempts() []models.LoginAttempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.LoginAttempt(nil), s.attempts...)
+}
+
+func (s *Store) GetOperatorByID(ctx context.Context, operatorID int) (*models.Operator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operator, ok := s.operators[operatorID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	result := *operator
+	result.AccessEvents = nil
+
+	return &result, nil
+}
+
+func (s *Store) GetOperatorByUsername(ctx context.Context, username string) (*models.Operator, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operator := s.findOperator(username)
+	if operator == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	result := *operator
+
+	return &result, nil
+}
+
+func (s *Store) GetRole(ctx context.Context, roleID int) (*models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, ok := s.roles[roleID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return &role, nil
+}
+
+func (s *Store) OperatorExists(ctx context.Context, username string, email *string, phone string) (usernameTaken, emailTaken, phoneTaken bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, operator := range s.operators {
+		usernameTaken = usernameTaken || operator.UserName == username
+		emailTaken = emailTaken || (email != nil && operator.Email != nil && *operator.Email == *email)
+		phoneTaken = phoneTaken || operator.Phone == phone
+	}
+
+	return usernameTaken, emailTaken, phoneTaken, nil
+}
+
+func (s *Store) CreateOperator(ctx context.Context, operator *models.Operator, hashedPassword string, classIDs []int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.operators {
+		if existing.UserName == operator.UserName || existing.Phone == operator.Phone ||
+			(operator.Email != nil && existing.Email != nil && *existing.Email == *operator.Email) {
+			return 0, repository.ErrOperatorExists
+		}
+	}
+
+	// Group the classes by event like the eager load of the Postgres store
+	var accessEvents []models.AccessEvent
+	eventIndex := map[int]int{}
+
+	for _, classID := range classIDs {
+		class, ok := s.classes[classID]
+		if !ok {
+			return 0, repository.ErrClassNotFound
+		}
+
+		i, ok := eventIndex[class.Event.ID]
+		if !ok {
+			i = len(accessEvents)
+			eventIndex[class.Event.ID] = i
+			accessEvents = append(accessEvents, models.AccessEvent{ID: class.Event.ID, Name: class.Event.Name})
+		}
+
+		name := class.Name
+		accessEvents[i].Classes = append(accessEvents[i].Classes, models.EventClass{ID: class.ID, Name: &name})
+	}
+
+	operator.IsLimitedClassAccess = len(classIDs) > 0
+	operator.IsLimitedEventAccess = len(classIDs) > 0
+	operator.ID = s.nextOperatorID
+	s.nextOperatorID++
+
+	stored := *operator
+	stored.Password = hashedPassword
+	stored.AccessEvents = accessEvents
+	s.operators[stored.ID] = &stored
+
+	return stored.ID, nil
+}
+
+func (s *Store) UpdateOperatorDeviceInformation(ctx context.Context, deviceID *string, deviceToken *string, operatorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if operator, ok := s.operators[operatorID]; ok {
+		operator.DeviceId = deviceID
+		operator.DeviceAccessToken = deviceToken
+	}
+
+	return nil
+}
+
+func (s *Store) RecordOperatorLoginFailure(ctx context.Context, operatorID int, lockedUntil *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if operator, ok := s.operators[operatorID]; ok {
+		operator.FailedLoginAttempts++
+
+		if lockedUntil != nil {
+			utc := lockedUntil.UTC()
+			operator.LockedUntil = &utc
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) ResetOperatorLoginFailures(ctx context.Context, operatorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if operator, ok := s.operators[operatorID]; ok {
+		operator.FailedLoginAttempts = 0
+		operator.LockedUntil = nil
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateOperatorPassword(ctx context.Context, operatorID int, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if operator, ok := s.operators[operatorID]; ok {
+		now := time.Now().UTC()
+
+		operator.Password = hashedPassword
+		operator.PasswordChangedAt = &now
+		operator.TokenVersion++
+		operator.DeviceAccessToken = nil
+		delete(s.resets, operatorID)
+	}
+
+	return nil
+}
+
+func (s *Store) SetOperatorPasswordResetCode(ctx context.Context, operatorID int, codeHash string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.operators[operatorID]; !ok {
+		return sql.ErrNoRows
+	}
+
+	expiresAt = expiresAt.UTC()
+	s.resets[operatorID] = models.PasswordReset{
+		OperatorID: operatorID,
+		CodeHash:   &codeHash,
+		ExpiresAt:  &expiresAt,
+	}
+
+	return nil
+}
+
+func (s *Store) GetOperatorPasswordReset(ctx context.Context, username string) (*models.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operator := s.findOperator(username)
+	if operator == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	reset, ok := s.resets[operator.ID]
+	if !ok {
+		reset = models.PasswordReset{OperatorID: operator.ID}
+	}
+
+	return &reset, nil
+}
+
+func (s *Store) GetLockedOperators(ctx context.Context) ([]models.LoginLockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var lockouts []models.LoginLockout
+
+	for _, operator := range s.operators {
+		if operator.LockedUntil == nil || !operator.LockedUntil.After(now) {
+			continue
+		}
+
+		operatorID := operator.ID
+		lockouts = append(lockouts, models.LoginLockout{
+			Scope:       "operator",
+			Key:         operator.UserName,
+			OperatorID:  &operatorID,
+			Failures:    operator.FailedLoginAttempts,
+			LockedUntil: *operator.LockedUntil,
+		})
+	}
+
+	sort.Slice(lockouts, func(i, j int) bool {
+		return lockouts[i].LockedUntil.After(lockouts[j].LockedUntil)
+	})
+
+	return lockouts, nil
+}
+
+func (s *Store) InsertLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts = append(s.attempts, attempt)
+
+	return nil
+}
+
+func (s *Store) GetAttendanceData(ctx context.Context, operatorID int) ([]models.SyncResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classIDs, ok := s.scanners[operatorID]
+	if !ok {
+		return nil, repository.ErrOperatorNotFound
+	}
+
+	var responses []models.SyncResponse
+
+	for _, classID := range classIDs {
+		for _, ticket := range s.sortedTickets() {
+			if ticket.ClassID != classID {
+				continue
+			}
+
+			responses = append(responses, models.SyncResponse{
+				ID:           ticket.ID,
+				InvoiceCode:  ticket.InvoiceCode,
+				TicketCode:   ticket.TicketCode,
+				AttendStatus: ticket.AttendStatus,
+				AttendTime:   ticket.AttendTime,
+				User:         ticket.User,
+				Class:        s.classes[ticket.ClassID],
+			})
+		}
+	}
+
+	return responses, nil
+}
+
+func (s *Store) GetAttendanceVersion(ctx context.Context, operatorID int) (models.AttendanceVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classIDs, ok := s.scanners[operatorID]
+	if !ok {
+		return models.AttendanceVersion{}, repository.ErrOperatorNotFound
+	}
+
+	var version models.AttendanceVersion
+
+	for _, classID := range classIDs {
+		for _, ticket := range s.tickets {
+			if ticket.ClassID != classID {
+				continue
+			}
+
+			version.Tickets++
+
+			if version.UpdatedAt == nil || ticket.updatedAt.After(*version.UpdatedAt) {
+				updatedAt := ticket.updatedAt
+				version.UpdatedAt = &updatedAt
+			}
+		}
+	}
+
+	return version, nil
+}
+
+func (s *Store) GetTicketTimeZones(ctx context.Context, ticketCodes []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zones := map[string]string{}
+	for _, code := range ticketCodes {
+		if ticket, ok := s.tickets[code]; ok {
+			zones[code] = s.classes[ticket.ClassID].Event.TimeZone
+		}
+	}
+
+	return zones, nil
+}
+
+func (s *Store) GetTicketClassID(ctx context.Context, ticketCode string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ticket, ok := s.tickets[ticketCode]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	return ticket.ClassID, nil
+}
+
+func (s *Store) UpdateAttendanceStatus(ctx context.Context, data []models.SyncDataUpdate, operatorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range data {
+		if _, ok := s.tickets[item.InvoiceCode]; !ok {
+			return repository.ErrTicketNotFound
+		}
+	}
+
+	for _, item := range data {
+		ticket := s.tickets[item.InvoiceCode]
+
+		// The earliest check-in wins, as in the Postgres store
+		if ticket.AttendStatus && ticket.AttendTime != nil && !item.AttendTime.Before(*ticket.AttendTime) {
+			continue
+		}
+
+		attendTime := item.AttendTime
+		attendOperatorID := operatorID
+
+		ticket.AttendStatus = true
+		ticket.AttendTime = &attendTime
+		ticket.AttendOperatorID = &attendOperatorID
+		ticket.updatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// findOperator looks an operator up by username. The caller holds the lock.
+func (s *Store) findOperator(username string) *models.Operator {
+	for _, operator := range s.operators {
+		if operator.UserName == username {
+			return operator
+		}
+	}
+
+	return nil
+}
+
+// sortedTickets returns the tickets in ID order. The caller holds the lock.
+func (s *Store) sortedTickets() []*Ticket {
+	tickets := make([]*Ticket, 0, len(s.tickets))
+	for _, ticket := range s.tickets {
+		tickets = append(tickets, ticket)
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].ID < tickets[j].ID
+	})
+
+	return tickets
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"attendance-app/internal/config"
+	"attendance-app/internal/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrOperatorNotFound = errors.New("operator_not_found")
+	ErrTicketNotFound   = errors.New("ticket_not_found")
+)
+
+// OperatorStore holds the operators that log in to the app, their roles and
+// their login history. Lookups return sql.ErrNoRows for missing operators.
+type OperatorStore interface {
+	GetOperatorByID(ctx context.Context, operatorID int) (*models.Operator, error)
+	GetOperatorByUsername(ctx context.Context, username string) (*models.Operator, error)
+	GetRole(ctx context.Context, roleID int) (*models.Role, error)
+	OperatorExists(ctx context.Context, username string, email *string, phone string) (usernameTaken, emailTaken, phoneTaken bool, err error)
+	CreateOperator(ctx context.Context, operator *models.Operator, hashedPassword string, classIDs []int) (int, error)
+	UpdateOperatorDeviceInformation(ctx context.Context, deviceID *string, deviceToken *string, operatorID int) error
+	RecordOperatorLoginFailure(ctx context.Context, operatorID int, lockedUntil *time.Time) error
+	ResetOperatorLoginFailures(ctx context.Context, operatorID int) error
+	UpdateOperatorPassword(ctx context.Context, operatorID int, hashedPassword string) error
+	SetOperatorPasswordResetCode(ctx context.Context, operatorID int, codeHash string, expiresAt time.Time) error
+	GetOperatorPasswordReset(ctx context.Context, username string) (*models.PasswordReset, error)
+	GetLockedOperators(ctx context.Context) ([]models.LoginLockout, error)
+	InsertLoginAttempt(ctx context.Context, attempt models.LoginAttempt) error
+}
+
+// AttendanceStore holds the tickets scanners download and check in.
+type AttendanceStore interface {
+	// GetAttendanceData returns ErrOperatorNotFound for unknown operators.
+	GetAttendanceData(ctx context.Context, operatorID int) ([]models.SyncResponse, error)
+	// GetAttendanceVersion is a cheap summary of the same data, to tell
+	// whether a scanner's copy is current. It returns ErrOperatorNotFound
+	// for unknown operators.
+	GetAttendanceVersion(ctx context.Context, operatorID int) (models.AttendanceVersion, error)
+	GetTicketClassID(ctx context.Context, ticketCode string) (int, error)
+	// GetTicketTimeZones returns the time zone of the event of every known
+	// ticket among the codes.
+	GetTicketTimeZones(ctx context.Context, ticketCodes []string) (map[string]string, error)
+	// UpdateAttendanceStatus checks in every ticket or, returning
+	// ErrTicketNotFound when one is unknown, none. A ticket keeps its
+	// earliest check-in.
+	UpdateAttendanceStatus(ctx context.Context, data []models.SyncDataUpdate, operatorID int) error
+}
+
+// Store is the Postgres implementation of the stores.
+type Store struct {
+	db           *pgxpool.Pool
+	queryTimeout time.Duration // Limit of a single lookup or update
+	syncTimeout  time.Duration // Limit of the queries of a sync
+}
+
+var (
+	_ OperatorStore   = (*Store)(nil)
+	_ AttendanceStore = (*Store)(nil)
+)
+
+// NewStore returns a store that runs its queries on the pool.
+func NewStore(db *pgxpool.Pool, cfg config.DatabaseConfig) *Store {
+	return &Store{
+		db:           db,
+		queryTimeout: cfg.QueryTimeout,
+		syncTimeout:  cfg.SyncTimeout,
+	}
+}
@@ -1,27 +1,32 @@
 package repository
 
 import (
-	db "attendance-app/internal/database"
+	"attendance-app/internal/logger"
 	"attendance-app/internal/models"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"log"
-	"strconv"
 	"strings"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // GetAttendanceData fetches attendance data for a given operator ID.
-func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
-	exists, err := IsOperatorExists(operatorId)
+func (s *Store) GetAttendanceData(ctx context.Context, operatorId int) ([]models.SyncResponse, error) {
+	ctx, cancel := s.withSyncTimeout(ctx)
+	defer cancel()
+
+	exists, err := s.IsOperatorExists(ctx, operatorId)
 
 	if err != nil {
-		log.Println("Error checking operator existence:", err)
+		logger.Println(ctx, "Error checking operator existence:", err)
 		return nil, err
 	}
 
 	if !exists {
-		log.Println("Operator not found with ID:", operatorId)
-		return nil, errors.New("operator_not_found")
+		logger.Println(ctx, "Operator not found with ID:", operatorId)
+		return nil, ErrOperatorNotFound
 	}
 
 	// Query to fetch attendance data based on operatorId
@@ -34,6 +39,7 @@ func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
             a.attend_time as attend_time,
             g.id as event_id,
             g.name as event_name,
+            g.timezone as event_timezone,
 			f.id as class_id,
             f.name as class_name,
             e.id as user_id,
@@ -53,9 +59,9 @@ func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
             c.operator_id ASC;
 	`
 
-	rows, err := db.DB.Query(query, operatorId)
+	rows, err := s.db.Query(ctx, query, operatorId)
 	if err != nil {
-		log.Println("Error querying database:", err)
+		logger.Println(ctx, "Error querying database:", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -77,6 +83,7 @@ func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
 			&response.AttendTime,
 			&event.ID,
 			&event.Name,
+			&event.TimeZone,
 			&class.ID,
 			&class.Name,
 			&user.ID,
@@ -84,7 +91,7 @@ func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
 			&user.Email,
 			&user.Phone,
 		); err != nil {
-			log.Println("Error scanning row:", err)
+			logger.Println(ctx, "Error scanning row:", err)
 			return nil, err
 		}
 
@@ -97,118 +104,256 @@ func GetAttendanceData(operatorId int) ([]models.SyncResponse, error) {
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Println("Error with rows:", err)
+		logger.Println(ctx, "Error with rows:", err)
 		return nil, err
 	}
 
 	return responses, nil
 }
 
-func IsOperatorExists(operatorId int) (bool, error) {
+// GetAttendanceVersion counts the tickets of the operator's classes and finds
+// their latest change, without reading the tickets themselves.
+func (s *Store) GetAttendanceVersion(ctx context.Context, operatorId int) (models.AttendanceVersion, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
+	exists, err := s.IsOperatorExists(ctx, operatorId)
+	if err != nil {
+		return models.AttendanceVersion{}, err
+	}
+
+	if !exists {
+		return models.AttendanceVersion{}, ErrOperatorNotFound
+	}
+
+	// Check-ins only set latest_sync_at, so it counts as a change too
+	query := `
+		SELECT
+			COUNT(a.id),
+			GREATEST(
+				MAX(a.updated_at), MAX(a.latest_sync_at), MAX(b.updated_at),
+				MAX(e.updated_at), MAX(f.updated_at), MAX(g.updated_at)
+			)
+		FROM
+			t_operators_classes c
+			JOIN t_transaction_details a ON a.class_id = c.class_id
+			LEFT JOIN t_transactions b ON b.id = a.transaction_id
+			LEFT JOIN m_users e ON e.id = b.user_id
+			LEFT JOIN m_classes f ON f.id = a.class_id
+			LEFT JOIN m_events g ON g.id = f.event_id
+		WHERE c.operator_id = $1`
+
+	var version models.AttendanceVersion
+	err = s.db.QueryRow(ctx, query, operatorId).Scan(&version.Tickets, &version.UpdatedAt)
+
+	return version, err
+}
+
+func (s *Store) IsOperatorExists(ctx context.Context, operatorId int) (bool, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT id from m_operators where id = $1`
 
-	var id string
+	var id int
 
-	err := db.DB.QueryRow(query, operatorId).Scan(&id)
+	err := s.db.QueryRow(ctx, query, operatorId).Scan(&id)
 
 	if err != nil {
-		if err.Error() == "sql: no rows in result set" {
+		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
 		}
 
 		// For other errors, log and return
-		log.Println("Error querying operator existence:", err)
+		logger.Println(ctx, "Error querying operator existence:", err)
 		return false, err
 	}
 
 	return true, nil
 }
 
-// CheckInvoicesExist checks if all invoice codes exist in the database.
-func CheckInvoicesExist(invoiceCodes []string) (bool, error) {
-	// Generate PostgreSQL placeholders dynamically
-	placeholders := make([]string, len(invoiceCodes))
-	args := make([]interface{}, len(invoiceCodes))
-
-	for i, code := range invoiceCodes {
-		placeholders[i] = "$" + strconv.Itoa(i+1) // PostgreSQL uses $1, $2, ...
-		args[i] = code
-	}
+// GetTicketClassID returns the class of a ticket.
+func (s *Store) GetTicketClassID(ctx context.Context, ticketCode string) (int, error) {
+	ctx, cancel := s.withQueryTimeout(ctx)
+	defer cancel()
 
 	query := `
-		SELECT COUNT(*) 
-		FROM t_transaction_details 
-		WHERE ticket_code IN (` + strings.Join(placeholders, ",") + `)`
+		SELECT class_id
+		FROM t_transaction_details
+		WHERE ticket_code = $1 AND deleted_at IS NULL`
+
+	var classID int
+	err := s.db.QueryRow(ctx, query, ticketCode).Scan(&classID)
 
-	log.Println("query", query)
-	log.Println("args", args)
+	return classID, err
+}
+
+// GetTicketTimeZones returns the time zone of the event of every known ticket
+// among the codes.
+func (s *Store) GetTicketTimeZones(ctx context.Context, ticketCodes []string) (map[string]string, error) {
+	ctx, cancel := s.withSyncTimeout(ctx)
+	defer cancel()
 
-	var count int
-	err := db.DB.QueryRow(query, args...).Scan(&count)
+	query := `
+		SELECT a.ticket_code, g.timezone
+		FROM
+			t_transaction_details a
+			JOIN m_classes f ON f.id = a.class_id
+			JOIN m_events g ON g.id = f.event_id
+		WHERE a.ticket_code = ANY($1)`
+
+	rows, err := s.db.Query(ctx, query, ticketCodes)
 	if err != nil {
-		log.Println("error", err)
-		return false, err
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := map[string]string{}
+
+	for rows.Next() {
+		var code, zone string
+		if err := rows.Scan(&code, &zone); err != nil {
+			return nil, err
+		}
+
+		zones[code] = zone
 	}
 
-	return count == len(invoiceCodes), nil
+	return zones, rows.Err()
 }
 
-func UpdateAttendanceStatus(data []models.SyncDataUpdate, operatorID int) error {
-	// Prepare the base query and arguments slice
-	query := `
-		UPDATE t_transaction_details t
-		SET 
-			attend_status = true, 
-			attend_time = v.attend_time,
-			attend_operator_id = v.attend_operator_id,
-			latest_sync_at = now()
-		FROM (VALUES`
-
-	args := []interface{}{}
-	// Loop through the data to build the query and append the values
+// copyThreshold is the upload size from which check-ins are staged with COPY
+// instead of sent as one UPDATE ... FROM (VALUES ...). Postgres caps a
+// statement at 65535 parameters, which the VALUES list reaches at about
+// 21k tickets, and COPY is also faster well before that.
+const copyThreshold = 1000
+
+// UpdateAttendanceStatus checks the uploaded tickets in for the operator. The
+// tickets are locked, checked and updated in one transaction, so an upload is
+// applied completely or, when a ticket is unknown, not at all.
+func (s *Store) UpdateAttendanceStatus(ctx context.Context, data []models.SyncDataUpdate, operatorID int) error {
+	ctx, cancel := s.withSyncTimeout(ctx)
+	defer cancel()
+
+	data = earliestScans(data)
+
+	codes := make([]string, len(data))
 	for i, item := range data {
-		// Add placeholder values for attend_time, operator_id, and invoice_code
-		if i > 0 {
-			query += ","
+		codes[i] = item.InvoiceCode
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock in ID order, so concurrent uploads sharing tickets wait for each
+	// other instead of deadlocking
+	var found int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM (
+			SELECT id
+			FROM t_transaction_details
+			WHERE ticket_code = ANY($1)
+			ORDER BY id
+			FOR UPDATE
+		) locked`, codes).Scan(&found)
+	if err != nil {
+		return err
+	}
+
+	if found != len(codes) {
+		return ErrTicketNotFound
+	}
+
+	if len(data) >= copyThreshold {
+		err = updateAttendanceCopy(ctx, tx, data, operatorID)
+	} else {
+		err = updateAttendanceValues(ctx, tx, data, operatorID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// earliestScans keeps the earliest scan of every ticket, in upload order.
+func earliestScans(data []models.SyncDataUpdate) []models.SyncDataUpdate {
+	index := make(map[string]int, len(data))
+	scans := make([]models.SyncDataUpdate, 0, len(data))
+
+	for _, item := range data {
+		i, ok := index[item.InvoiceCode]
+		if !ok {
+			index[item.InvoiceCode] = len(scans)
+			scans = append(scans, item)
+		} else if item.AttendTime.Before(scans[i].AttendTime) {
+			scans[i] = item
 		}
-		// Append values for this row
-		query += fmt.Sprintf("($%d::timestamp, $%d::int, $%d)", i*3+1, i*3+2, i*3+3)
+	}
+
+	return scans
+}
+
+// attendanceUpdate merges check-ins from the relation %s, aliased v, into the tickets. A
+// ticket keeps its earliest check-in, so uploads from several scanners end the
+// same whichever commits first.
+const attendanceUpdate = `
+	UPDATE t_transaction_details t
+	SET
+		attend_status = true,
+		attend_time = v.attend_time,
+		attend_operator_id = v.attend_operator_id,
+		latest_sync_at = now()
+	FROM %s
+	WHERE t.ticket_code = v.ticket_code
+		AND (NOT t.attend_status OR t.attend_time IS NULL OR v.attend_time < t.attend_time)`
+
+// updateAttendanceValues updates the tickets with a single statement, three
+// parameters per ticket.
+func updateAttendanceValues(ctx context.Context, tx pgx.Tx, data []models.SyncDataUpdate, operatorID int) error {
+	values := make([]string, len(data))
+	args := make([]interface{}, 0, len(data)*3)
 
-		// Append the corresponding arguments for each row
+	for i, item := range data {
+		values[i] = fmt.Sprintf("($%d::timestamptz, $%d::int, $%d)", i*3+1, i*3+2, i*3+3)
 		args = append(args, item.AttendTime, operatorID, item.InvoiceCode)
 	}
 
-	// Complete the query by closing the FROM clause and adding the WHERE clause
-	query += `) AS v(attend_time, attend_operator_id, ticket_code)
-	WHERE t.ticket_code = v.ticket_code`
+	relation := "(VALUES " + strings.Join(values, ",") + ") AS v(attend_time, attend_operator_id, ticket_code)"
 
-	// Execute the query
-	_, err := db.DB.Exec(query, args...)
+	_, err := tx.Exec(ctx, fmt.Sprintf(attendanceUpdate, relation), args...)
 	return err
 }
 
-func UpdateAttendanceStatusOld(data []models.SyncDataUpdate, operatorID int) error {
-	// Prepare the base query
-	query := `
-		UPDATE t_transaction_details
-		SET 
-			attend_status = true, 
-			attend_time = $1,
-			attend_operator_id = $2,
-			latest_sync_at = now()
-		WHERE ticket_code = $3`
-
-	// Loop through the data and update each record individually
-	for _, item := range data {
-		// Execute the update query for each record
-		_, err := db.DB.Exec(query, item.AttendTime, operatorID, item.InvoiceCode)
-		if err != nil {
-			// Log the error and continue, or return depending on the error handling strategy
-			log.Printf("Error updating attendance status for ticket %s: %v", item.InvoiceCode, err)
-			// Optionally, return the error if you want to stop on failure
-			return err
-		}
+// updateAttendanceCopy stages the tickets in a temporary table with COPY and
+// merges them in one UPDATE.
+func updateAttendanceCopy(ctx context.Context, tx pgx.Tx, data []models.SyncDataUpdate, operatorID int) error {
+	_, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE attendance_upload (
+			ticket_code VARCHAR(255) NOT NULL,
+			attend_time TIMESTAMPTZ NOT NULL,
+			attend_operator_id INT NOT NULL
+		) ON COMMIT DROP`)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"attendance_upload"},
+		[]string{"ticket_code", "attend_time", "attend_operator_id"},
+		pgx.CopyFromSlice(len(data), func(i int) ([]any, error) {
+			return []any{data[i].InvoiceCode, data[i].AttendTime, operatorID}, nil
+		}),
+	)
+	if err != nil {
+		logger.Println(ctx, "Error copying attendance upload:", err)
+		return err
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(attendanceUpdate, "attendance_upload AS v"))
+	return err
 }
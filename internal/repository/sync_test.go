@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"attendance-app/internal/migrations"
+	"attendance-app/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// These run against a real Postgres, set TEST_DATABASE_URL to a throwaway
+// database to enable them:
+//
+//	TEST_DATABASE_URL=postgres://localhost/attendance_test go test -run UpdateAttendance -bench . ./internal/repository/
+
+func testStore(tb testing.TB) *Store {
+	tb.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		tb.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(pool.Close)
+
+	if err := migrations.NewRunner(pool, io.Discard).Up(ctx, 0, false); err != nil {
+		tb.Fatal(err)
+	}
+
+	return &Store{db: pool, queryTimeout: time.Minute, syncTimeout: 10 * time.Minute}
+}
+
+// seedTickets creates an operator account and n unattended tickets, removed
+// again when the test ends, and returns the upload that checks all of them in.
+func seedTickets(tb testing.TB, s *Store, n int) (int, []models.SyncDataUpdate) {
+	tb.Helper()
+
+	ctx := context.Background()
+	suffix := fmt.Sprint(time.Now().UnixNano())
+
+	var userID, eventID, classID, operatorID, transactionID int
+	email := "bench-" + suffix + "@example.com"
+
+	err := s.db.QueryRow(ctx, `INSERT INTO m_users (name, email, phone, password) VALUES ('bench', $1, $2, '') RETURNING id`, email, suffix).Scan(&userID)
+	if err == nil {
+		err = s.db.QueryRow(ctx, `INSERT INTO m_events (name) VALUES ('bench') RETURNING id`).Scan(&eventID)
+	}
+	if err == nil {
+		err = s.db.QueryRow(ctx, `INSERT INTO m_classes (event_id, name) VALUES ($1, 'bench') RETURNING id`, eventID).Scan(&classID)
+	}
+	if err == nil {
+		err = s.db.QueryRow(ctx, `INSERT INTO m_admin_attendances (name, username, phone, password) VALUES ('bench', $1, $2, '') RETURNING id`, email, suffix).Scan(&operatorID)
+	}
+	if err == nil {
+		err = s.db.QueryRow(ctx, `INSERT INTO t_transactions (amount, invoice_code, transaction_status, user_id) VALUES (0, $1, 'paid', $2) RETURNING id`, "BENCH-"+suffix, userID).Scan(&transactionID)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(func() {
+		// The tickets and transaction go with the user and event by cascade
+		s.db.Exec(ctx, `DELETE FROM m_users WHERE id = $1`, userID)
+		s.db.Exec(ctx, `DELETE FROM m_events WHERE id = $1`, eventID)
+		s.db.Exec(ctx, `DELETE FROM m_admin_attendances WHERE id = $1`, operatorID)
+	})
+
+	attendTime := time.Date(2024, 10, 19, 9, 30, 0, 0, time.UTC)
+	data := make([]models.SyncDataUpdate, n)
+
+	_, err = s.db.CopyFrom(ctx,
+		pgx.Identifier{"t_transaction_details"},
+		[]string{"transaction_id", "class_id", "ticket_code"},
+		pgx.CopyFromSlice(n, func(i int) ([]any, error) {
+			data[i] = models.SyncDataUpdate{
+				InvoiceCode: fmt.Sprintf("BENCH-%s-%d", suffix, i),
+				AttendTime:  attendTime,
+			}
+			return []any{transactionID, classID, data[i].InvoiceCode}, nil
+		}),
+	)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return operatorID, data
+}
+
+// TestUpdateAttendanceStatusLargeUpload checks an upload past the parameter
+// limit of the VALUES statement is applied in full.
+func TestUpdateAttendanceStatusLargeUpload(t *testing.T) {
+	s := testStore(t)
+	operatorID, data := seedTickets(t, s, 30000)
+
+	ctx := context.Background()
+
+	if err := s.UpdateAttendanceStatus(ctx, data, operatorID); err != nil {
+		t.Fatal(err)
+	}
+
+	var attended int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM t_transaction_details
+		WHERE ticket_code = ANY($1) AND attend_status AND attend_operator_id = $2 AND attend_time = $3`,
+		ticketCodes(data), operatorID, data[0].AttendTime).Scan(&attended)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attended != len(data) {
+		t.Errorf("%d tickets attended, want %d", attended, len(data))
+	}
+}
+
+// TestUpdateAttendanceStatusConcurrent checks that uploads racing for the
+// same tickets keep the earliest check-in whichever commits first.
+func TestUpdateAttendanceStatusConcurrent(t *testing.T) {
+	s := testStore(t)
+	operatorID, data := seedTickets(t, s, 2000)
+
+	ctx := context.Background()
+	earliest := data[0].AttendTime
+
+	// Each scanner uploads every ticket, later scanners with later times and
+	// in a different order
+	uploads := make([][]models.SyncDataUpdate, 4)
+	for u := range uploads {
+		for i := range data {
+			item := data[(i+u*500)%len(data)]
+			item.AttendTime = earliest.Add(time.Duration(u) * time.Minute)
+			uploads[u] = append(uploads[u], item)
+		}
+	}
+
+	errs := make(chan error, len(uploads))
+	for _, upload := range uploads {
+		go func() {
+			errs <- s.UpdateAttendanceStatus(ctx, upload, operatorID)
+		}()
+	}
+
+	for range uploads {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var attended int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM t_transaction_details
+		WHERE ticket_code = ANY($1) AND attend_status AND attend_time = $2`,
+		ticketCodes(data), earliest).Scan(&attended)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attended != len(data) {
+		t.Errorf("%d tickets kept the earliest check-in, want %d", attended, len(data))
+	}
+}
+
+// TestUpdateAttendanceStatusUnknownTicket checks an upload with an unknown
+// ticket changes nothing.
+func TestUpdateAttendanceStatusUnknownTicket(t *testing.T) {
+	s := testStore(t)
+	operatorID, data := seedTickets(t, s, 10)
+
+	ctx := context.Background()
+	upload := append(data, models.SyncDataUpdate{InvoiceCode: "UNKNOWN", AttendTime: data[0].AttendTime})
+
+	if err := s.UpdateAttendanceStatus(ctx, upload, operatorID); !errors.Is(err, ErrTicketNotFound) {
+		t.Fatalf("UpdateAttendanceStatus() = %v, want ErrTicketNotFound", err)
+	}
+
+	var attended int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM t_transaction_details
+		WHERE ticket_code = ANY($1) AND attend_status`,
+		ticketCodes(data)).Scan(&attended)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attended != 0 {
+		t.Errorf("%d tickets attended, want 0", attended)
+	}
+}
+
+func BenchmarkUpdateAttendanceStatus(b *testing.B) {
+	s := testStore(b)
+	ctx := context.Background()
+
+	paths := []struct {
+		name   string
+		update func(context.Context, pgx.Tx, []models.SyncDataUpdate, int) error
+	}{
+		{"values", updateAttendanceValues},
+		{"copy", updateAttendanceCopy},
+	}
+
+	// The VALUES statement cannot go past 21845 tickets
+	for _, size := range []int{100, 1000, 10000, 20000} {
+		operatorID, data := seedTickets(b, s, size)
+
+		for _, path := range paths {
+			b.Run(fmt.Sprintf("%s/%d", path.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					tx, err := s.db.Begin(ctx)
+					if err != nil {
+						b.Fatal(err)
+					}
+
+					// Rolled back, so every iteration checks the tickets in again
+					err = path.update(ctx, tx, data, operatorID)
+					tx.Rollback(ctx)
+
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func ticketCodes(data []models.SyncDataUpdate) []string {
+	codes := make([]string, len(data))
+	for i, item := range data {
+		codes[i] = item.InvoiceCode
+	}
+
+	return codes
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+)
+
+// withQueryTimeout limits a single lookup or update. The query is also
+// cancelled when the request it serves is.
+func (s *Store) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// withSyncTimeout limits the queries of a sync, which touch many tickets.
+func (s *Store) withSyncTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.syncTimeout)
+}
@@ -0,0 +1,138 @@
+package tickets
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Signed payloads look like "ATQR1.<payload>.<signature>", both parts base64url
+// without padding. The signature covers "ATQR1.<payload>".
+const (
+	payloadPrefix = "ATQR1"
+	Algorithm     = "Ed25519"
+)
+
+var (
+	ErrNotConfigured    = errors.New("ticket signing key is not configured")
+	ErrInvalidPayload   = errors.New("invalid ticket payload")
+	ErrInvalidSignature = errors.New("invalid ticket signature")
+	ErrUnknownKey       = errors.New("ticket is signed for another key")
+	ErrOutsideValidity  = errors.New("ticket is outside its validity window")
+)
+
+// Payload is the content of a signed ticket QR code.
+type Payload struct {
+	TicketCode string `json:"t"`
+	ClassID    int    `json:"c"`
+	NotBefore  int64  `json:"nbf"`
+	ExpiresAt  int64  `json:"exp"`
+	KeyID      string `json:"kid"`
+}
+
+// VerificationKey is published to scanners so they can verify payloads offline.
+type VerificationKey struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Format    string `json:"format"`
+}
+
+// Signer signs and verifies ticket payloads with an Ed25519 key.
+type Signer struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	keyID      string
+}
+
+// NewSigner creates a signer from a 32 byte Ed25519 seed.
+func NewSigner(seed []byte) (*Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ticket signing key must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	fingerprint := sha256.Sum256(publicKey)
+
+	return &Signer{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		keyID:      hex.EncodeToString(fingerprint[:8]),
+	}, nil
+}
+
+// Sign returns the QR payload for a ticket valid between notBefore and expiresAt.
+func (s *Signer) Sign(ticketCode string, classID int, notBefore, expiresAt time.Time) (string, error) {
+	payload := Payload{
+		TicketCode: ticketCode,
+		ClassID:    classID,
+		NotBefore:  notBefore.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+		KeyID:      s.keyID,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	signed := payloadPrefix + "." + base64.RawURLEncoding.EncodeToString(body)
+	signature := ed25519.Sign(s.privateKey, []byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks the signature, key ID and validity window of a QR payload at
+// the given time. Scanners implement the same steps with the published key.
+func (s *Signer) Verify(value string, at time.Time) (*Payload, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] != payloadPrefix {
+		return nil, ErrInvalidPayload
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	if !ed25519.Verify(s.publicKey, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrInvalidPayload
+	}
+
+	if payload.KeyID != s.keyID {
+		return nil, ErrUnknownKey
+	}
+
+	if at.Unix() < payload.NotBefore || at.Unix() > payload.ExpiresAt {
+		return nil, ErrOutsideValidity
+	}
+
+	return &payload, nil
+}
+
+// VerificationKey returns the public half of the signing key.
+func (s *Signer) VerificationKey() VerificationKey {
+	return VerificationKey{
+		Algorithm: Algorithm,
+		KeyID:     s.keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(s.publicKey),
+		Format:    payloadPrefix,
+	}
+}
@@ -0,0 +1,140 @@
+package tickets
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	notBefore = time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	expiresAt = notBefore.Add(24 * time.Hour)
+)
+
+func newTestSigner(t *testing.T, seed byte) *Signer {
+	t.Helper()
+
+	signer, err := NewSigner(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+func sign(t *testing.T, signer *Signer) string {
+	t.Helper()
+
+	value, err := signer.Sign("TCK-1", 10, notBefore, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	signer := newTestSigner(t, 1)
+
+	payload, err := signer.Verify(sign(t, signer), notBefore.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Payload{
+		TicketCode: "TCK-1",
+		ClassID:    10,
+		NotBefore:  notBefore.Unix(),
+		ExpiresAt:  expiresAt.Unix(),
+		KeyID:      signer.VerificationKey().KeyID,
+	}
+	if *payload != want {
+		t.Errorf("payload = %+v, want %+v", *payload, want)
+	}
+}
+
+func TestVerifyValidityWindow(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	value := sign(t, signer)
+
+	tests := []struct {
+		at   time.Time
+		want error
+	}{
+		{notBefore.Add(-time.Second), ErrOutsideValidity},
+		{notBefore, nil},
+		{expiresAt, nil},
+		{expiresAt.Add(time.Second), ErrOutsideValidity},
+	}
+
+	for _, test := range tests {
+		if _, err := signer.Verify(value, test.at); !errors.Is(err, test.want) {
+			t.Errorf("Verify at %v = %v, want %v", test.at, err, test.want)
+		}
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	parts := strings.Split(sign(t, signer), ".")
+
+	// Move the ticket to another class, keeping the signature
+	body, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	body = bytes.Replace(body, []byte(`"c":10`), []byte(`"c":20`), 1)
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(body) + "." + parts[2]
+
+	if _, err := signer.Verify(forged, notBefore); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("forged payload: %v, want %v", err, ErrInvalidSignature)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	signature[0] ^= 1
+	flipped := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	if _, err := signer.Verify(flipped, notBefore); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("altered signature: %v, want %v", err, ErrInvalidSignature)
+	}
+
+	for _, value := range []string{"", "TCK-1", "ATQR2." + parts[1] + "." + parts[2], parts[0] + "." + parts[1] + ".!!"} {
+		if _, err := signer.Verify(value, notBefore); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("Verify(%q) = %v, want %v", value, err, ErrInvalidPayload)
+		}
+	}
+}
+
+func TestVerifyRejectsOtherKey(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	other := newTestSigner(t, 2)
+
+	if _, err := signer.Verify(sign(t, other), notBefore); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("payload of another key: %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyChecksKeyID(t *testing.T) {
+	signer := newTestSigner(t, 1)
+
+	// Signed with the right key but naming another one
+	body, err := json.Marshal(Payload{TicketCode: "TCK-1", ClassID: 10, NotBefore: notBefore.Unix(), ExpiresAt: expiresAt.Unix(), KeyID: "0000000000000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := payloadPrefix + "." + base64.RawURLEncoding.EncodeToString(body)
+	value := signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(signer.privateKey, []byte(signed)))
+
+	if _, err := signer.Verify(value, notBefore); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Verify = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestNewSignerRejectsShortSeed(t *testing.T) {
+	if _, err := NewSigner(make([]byte, 16)); err == nil {
+		t.Error("NewSigner accepted a 16 byte seed")
+	}
+}
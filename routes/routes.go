@@ -2,18 +2,50 @@ package routes
 
 import (
 	"attendance-app/internal/api"
+	"attendance-app/internal/auth"
+	"attendance-app/internal/config"
+	"attendance-app/internal/metrics"
+	"attendance-app/internal/middleware"
+	"attendance-app/internal/repository"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func SetupRoutes() *mux.Router {
+func SetupRoutes(cfg *config.Config, h *api.Handler, tokens *auth.TokenIssuer, operators repository.OperatorStore) *mux.Router {
 	router := mux.NewRouter()
+	router.Use(middleware.RequestID, middleware.Metrics, middleware.AccessLog)
+
+	// Probes for the load balancer and the ngrok tunnel, no authentication
+	router.HandleFunc("/healthz", h.HealthzHandler).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", h.ReadyzHandler).Methods(http.MethodGet)
+	router.HandleFunc("/version", h.VersionHandler).Methods(http.MethodGet)
+	router.Handle("/metrics", middleware.RequireBearerToken(cfg.Server.MetricsToken)(metrics.Handler())).Methods(http.MethodGet)
 
 	// Add routes
-	router.HandleFunc("/api/v1/sync/{operatorId}", api.SyncHandler).Methods(http.MethodGet)
-	router.HandleFunc("/api/v1/sync", api.SyncPutHandler).Methods(http.MethodPut)
-	router.HandleFunc("/api/v1/operator/login", api.LoginHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/operator/login", h.LoginHandler).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/operator/password/reset", h.ResetPasswordHandler).Methods(http.MethodPost)
+
+	// Routes that require a logged in, active operator
+	protected := router.NewRoute().Subrouter()
+	protected.Use(middleware.Authenticate(tokens, operators))
+
+	protected.HandleFunc("/api/v1/operator/password", h.ChangePasswordHandler).Methods(http.MethodPut)
+	// Sync payloads are large and repetitive, so they may travel compressed
+	protected.Handle("/api/v1/sync/{operatorId}", middleware.Compress(guarded(auth.PermissionSyncAttendance, h.SyncHandler))).Methods(http.MethodGet)
+	protected.Handle("/api/v1/sync", middleware.Decompress(guarded(auth.PermissionSyncAttendance, h.SyncPutHandler))).Methods(http.MethodPut)
+	protected.Handle("/api/v1/tickets/{ticketCode}/signed", guarded(auth.PermissionIssueTickets, h.SignedTicketHandler)).Methods(http.MethodGet)
+
+	// Admin routes
+	protected.Handle("/api/v1/admin/lockouts", guarded(auth.PermissionViewLockouts, h.LockoutsHandler)).Methods(http.MethodGet)
+	protected.Handle("/api/v1/admin/lockouts/{username}", guarded(auth.PermissionManageOperator, h.UnlockOperatorHandler)).Methods(http.MethodDelete)
+	protected.Handle("/api/v1/admin/operators", guarded(auth.PermissionManageOperator, h.RegisterOperatorHandler)).Methods(http.MethodPost)
+	protected.Handle("/api/v1/admin/operators/{operatorId}/password-reset", guarded(auth.PermissionResetPassword, h.AdminResetPasswordHandler)).Methods(http.MethodPost)
 
 	return router
 }
+
+// guarded only lets operators whose role grants the permission reach the handler.
+func guarded(permission auth.Permission, handler http.HandlerFunc) http.Handler {
+	return middleware.RequirePermission(permission)(handler)
+}